@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func BenchmarkParse(b *testing.B) {
+	sources, err := filepath.Glob(filepath.Join("testdata", "corpus", "*", "source.html"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, source := range sources {
+		s, err := os.ReadFile(source)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(filepath.Base(filepath.Dir(source)), func(b *testing.B) {
+			benchmarkParse(b, string(s))
+		})
+	}
+}
+
+// 大页面：深层嵌套的正文、侧边栏、评论区以及大量链接，用于衡量评分与清理阶段的开销
+func BenchmarkParseLarge(b *testing.B) {
+	for _, paragraphs := range []int{100, 500, 2000} {
+		b.Run(fmt.Sprintf("paragraphs-%d", paragraphs), func(b *testing.B) {
+			benchmarkParse(b, largePage(paragraphs))
+		})
+	}
+}
+
+func benchmarkParse(b *testing.B, s string) {
+	b.SetBytes(int64(len(s)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := New(Option{PageURL: corpusPageURL}).Parse(s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func largePage(paragraphs int) string {
+	var b strings.Builder
+	b.WriteString(`<html><head><meta charset="utf-8"><title>大页面基准测试 - 示例站点</title>` +
+		`<meta name="description" content="用于基准测试的大页面"><meta property="og:title" content="大页面基准测试"></head><body>`)
+	b.WriteString(`<div class="header"><ul class="menu">`)
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&b, `<li><a href="/c/%d">栏目 %d</a></li>`, i, i)
+	}
+	b.WriteString(`</ul></div><div class="main"><div class="wrapper"><div class="article-body">`)
+	for i := 0; i < paragraphs; i++ {
+		if i%20 == 0 {
+			fmt.Fprintf(&b, `<h2>第 %d 节</h2><div class="section">`, i/20+1)
+		}
+		fmt.Fprintf(&b, `<p>这是第 %d 段正文，包含一些逗号，顿号、以及<a href="/ref/%d">相关链接</a>。`+
+			`The quick brown fox jumps over the lazy dog, again and again, until the paragraph is long enough.</p>`, i, i)
+		if i%20 == 19 {
+			b.WriteString(`<table><tr><td><img src="a.png"><img src="b.png"></td><td>广告</td></tr></table></div>`)
+		}
+	}
+	if paragraphs%20 != 0 {
+		b.WriteString(`</div>`)
+	}
+	b.WriteString(`</div></div><div class="sidebar">`)
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&b, `<div class="widget"><a href="/hot/%d">热门文章 %d</a><span>，评论 %d</span></div>`, i, i, i)
+	}
+	b.WriteString(`</div><div class="comments">`)
+	for i := 0; i < paragraphs/5; i++ {
+		fmt.Fprintf(&b, `<div class="comment"><p>网友 %d：说得好，支持一下。</p></div>`, i)
+	}
+	b.WriteString(`</div></div><div class="footer">版权所有</div></body></html>`)
+	return b.String()
+}
@@ -0,0 +1,294 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// 中文新闻页面常以纯文本形式给出作者、来源和编辑，例如 "作者：张三"、"来源：人民网"、
+// "责任编辑：李四"、"记者 王五"，很少带有 byline/author 之类的 class。
+
+var (
+	textBylineKeyPattern   = regexp.MustCompile(`(作者|记者|来源|出处|责任编辑|责编|编辑)[：:\s　]+`)
+	textBylineFieldPattern = regexp.MustCompile(`[\s　，,、；;|｜/（）()【】\[\]：:]+`)
+	// 作者信息行中的时间、日期，如 "2020-03-03 10:00"、"March 3, 2020"
+	bylineDatePattern = regexp.MustCompile(`(?i)(发布|更新)?(时间|日期)[：:]|\d{2,4}\s*[-/年.]\s*\d{1,2}\s*[-/月.]\s*\d{1,2}\s*日?|\d{1,2}:\d{2}(:\d{2})?|` +
+		`\b(jan(uary)?|feb(ruary)?|mar(ch)?|apr(il)?|may|june?|july?|aug(ust)?|sept?(ember)?|oct(ober)?|nov(ember)?|dec(ember)?)\.?\s+\d{1,2}(st|nd|rd|th)?\b|\b(19|20)\d{2}\b`)
+	// 作者信息行中常见的时间、日期等附属文字
+	bylineResiduePattern = regexp.MustCompile(bylineDatePattern.String() + `|[\s　\p{P}]`)
+)
+
+// 作者信息行去掉作者、来源、日期等之后最多允许剩余的字符数
+const maxBylineResidue = 10
+
+// 是否是纯文本形式的作者/来源/编辑信息行，是则填入 Byline、Authors、Source、Editor
+func (read *Readability) checkTextByline(s *goquery.Selection) bool {
+	// 先排除含有块级元素的节点，避免对大段正文调用 Text()
+	if hasChildBlockElement(s) {
+		return false
+	}
+	innerText := s.Text()
+	if !isValidByline(innerText) {
+		return false
+	}
+	keys := textBylineKeyPattern.FindAllStringSubmatchIndex(innerText, -1)
+	if len(keys) == 0 {
+		return false
+	}
+	var authors []Author
+	var source, editor string
+	residue := innerText[:keys[0][0]]
+	for i, k := range keys {
+		end := len(innerText)
+		if i+1 < len(keys) {
+			end = keys[i+1][0]
+		}
+		key := innerText[k[2]:k[3]]
+		fields := strings.Fields(textBylineFieldPattern.ReplaceAllString(innerText[k[1]:end], " "))
+		used := 0
+		switch key {
+		case "作者", "记者":
+			role := ""
+			if key == "记者" {
+				role = key
+			}
+			for _, name := range leadingNames(fields) {
+				authors = append(authors, Author{Name: name, Role: role})
+				used += len(strings.Fields(name))
+			}
+		case "来源", "出处":
+			if len(fields) > 0 {
+				if len(source) == 0 {
+					source = fields[0]
+				}
+				used = 1
+			}
+		default:
+			if len(fields) > 0 {
+				if len(editor) == 0 {
+					editor = fields[0]
+				}
+				used = 1
+			}
+		}
+		rest := strings.Join(fields[used:], "")
+		// 以空格而非冒号分隔时只能跟姓名与日期，如 "记者 王五"，以免把 "记者 昨日走访了多家医院。" 这样的正文当作作者信息
+		if !strings.ContainsAny(innerText[k[0]:k[1]], "：:") &&
+			(strings.ContainsAny(innerText[k[1]:end], "。！？!?") || len(bylineResiduePattern.ReplaceAllString(rest, "")) > 0) {
+			return false
+		}
+		residue += rest
+	}
+	residue = bylineResiduePattern.ReplaceAllString(residue, "")
+	if textLength(residue) > maxBylineResidue || len(authors)+len(source)+len(editor) == 0 {
+		return false
+	}
+	if len(read.article.Byline) == 0 && len(authors) > 0 {
+		read.article.Byline = joinAuthors(authors)
+		read.article.Authors = authors
+	}
+	if len(read.article.Source) == 0 {
+		read.article.Source = source
+	}
+	if len(read.article.Editor) == 0 {
+		read.article.Editor = editor
+	}
+	return true
+}
+
+var (
+	bylinePrefixPattern    = regexp.MustCompile(`(?i)^\s*(written\s+by|posted\s+by|by|authors?\s*[：:]|作者[：:]?|文[：:/]|文\s*/)\s*`)
+	authorSeparatorPattern = regexp.MustCompile(`(?i)\s*(?:,|，|、|;|；|&|＆|\||｜|/|\s+and\s+|\s+und\s+|\s+et\s+|\s+和\s*|\s+与\s*)\s*`)
+	authorRolePattern      = regexp.MustCompile(`(?i)^(staff\s+writer|senior\s+writer|writer|editor|reporter|correspondent|contributor|photographer|columnist|记者|编辑|通讯员|特约撰稿人|实习生)$`)
+	chineseNamePattern     = regexp.MustCompile(`^\p{Han}{2,4}$|^\p{Han}+·\p{Han}+$`)
+	latinNamePattern       = regexp.MustCompile(`^[\p{Latin}][\p{Latin}.'\-]*$`)
+	// 不带空格连接两个中文姓名的“和”“与”，如 "张三和李四"
+	chineseConjunctionPattern = regexp.MustCompile(`[和与]`)
+	// 作者主页的地址，如 /author/jane、/people/jane、?author=1
+	authorHrefPattern = regexp.MustCompile(`(?i)/(?:authors?|people|persons?|profiles?|users?|u|staff|writers?|contributors?|columnists?|reporters?|journalists?)/[^/?#]+|[?&]author=`)
+	// 与 Mozilla Readability 一致的 JSON-LD 文章类型
+	jsonLDArticleTypePattern = regexp.MustCompile(`^(Article|AdvertiserContentArticle|NewsArticle|AnalysisNewsArticle|AskPublicNewsArticle|BackgroundNewsArticle|OpinionNewsArticle|ReportageNewsArticle|ReviewNewsArticle|Report|SatiricalArticle|ScholarlyArticle|MedicalScholarlyArticle|SocialMediaPosting|BlogPosting|LiveBlogPosting|DiscussionForumPosting|TechArticle|APIReference)$`)
+)
+
+// Author 作者信息
+type Author struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+	Role string `json:"role,omitempty"`
+}
+
+// 将作者信息行拆分为多个作者，如 "By Jane Doe and John Smith"、"张三 李四"
+func splitByline(byline string, links map[string]string) []Author {
+	byline = bylinePrefixPattern.ReplaceAllString(normalizeSpace(ts(byline)), "")
+	var authors []Author
+	for _, part := range authorSeparatorPattern.Split(byline, -1) {
+		// 去掉日期、时间，如 "Jane Doe, March 3, 2020" 中的 "March 3" 与 "2020"
+		part = ts(bylineDatePattern.ReplaceAllString(part, ""))
+		if len(part) == 0 {
+			continue
+		}
+		if authorRolePattern.MatchString(part) {
+			if len(authors) > 0 && len(authors[len(authors)-1].Role) == 0 {
+				authors[len(authors)-1].Role = part
+			}
+			continue
+		}
+		// 以空格或“和”“与”分隔的多个中文姓名
+		names := strings.Fields(part)
+		if len(names) < 2 || !allMatch(names, chineseNamePattern) {
+			names = chineseConjunctionPattern.Split(part, -1)
+		}
+		if len(names) < 2 || !allMatch(names, chineseNamePattern) {
+			names = []string{part}
+		}
+		for _, name := range names {
+			authors = append(authors, Author{Name: name, URL: links[name]})
+		}
+	}
+	return authors
+}
+
+// 作者信息行中开头的若干个姓名，相邻的拉丁字母单词合并为一个姓名
+func leadingNames(fields []string) []string {
+	var names []string
+	latin := false
+	for _, f := range fields {
+		switch {
+		case chineseNamePattern.MatchString(f):
+			names = append(names, f)
+			latin = false
+		case latinNamePattern.MatchString(f):
+			if latin {
+				names[len(names)-1] += " " + f
+			} else {
+				names = append(names, f)
+			}
+			latin = true
+		default:
+			return names
+		}
+	}
+	return names
+}
+
+// 拼接作者姓名作为 Byline 显示
+func joinAuthors(authors []Author) string {
+	names := make([]string, 0, len(authors))
+	chinese := true
+	for _, a := range authors {
+		names = append(names, a.Name)
+		chinese = chinese && chineseNamePattern.MatchString(a.Name)
+	}
+	if chinese {
+		return strings.Join(names, "、")
+	}
+	return strings.Join(names, ", ")
+}
+
+// 收集节点中作者链接的 文字 -> 地址，只取 rel="author" 与指向作者主页的链接
+func (read *Readability) authorLinks(s *goquery.Selection) map[string]string {
+	links := make(map[string]string)
+	s.Find("a").AddSelection(s.Filter("a")).Each(func(i int, a *goquery.Selection) {
+		href, has := a.Attr("href")
+		if !has || strings.HasPrefix(href, "javascript:") {
+			return
+		}
+		if inSlice(strings.Fields(strings.ToLower(a.AttrOr("rel", ""))), "author") ||
+			a.AttrOr("itemprop", "") == "author" || authorHrefPattern.MatchString(href) {
+			links[normalizeSpace(ts(a.Text()))] = read.resolveURL(href)
+		}
+	})
+	return links
+}
+
+// 在移除 script 之前，从 JSON-LD 中读取作者信息
+func (read *Readability) getJSONLDAuthors() []Author {
+	var authors []Author
+	read.dom.Find(`script[type="application/ld+json"]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		var data interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil {
+			read.l("JSON-LD 解析失败", err)
+			return true
+		}
+		authors = jsonLDAuthors(data, read)
+		return len(authors) == 0
+	})
+	return authors
+}
+
+// 在 JSON-LD 对象（或其 @graph）中查找文章类型的节点并取出 author
+func jsonLDAuthors(data interface{}, read *Readability) []Author {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if authors := jsonLDAuthors(item, read); len(authors) > 0 {
+				return authors
+			}
+		}
+	case map[string]interface{}:
+		if graph, has := v["@graph"]; has {
+			return jsonLDAuthors(graph, read)
+		}
+		if isJSONLDArticle(v["@type"]) {
+			return jsonLDPersons(v["author"], read)
+		}
+	}
+	return nil
+}
+
+// 解析 JSON-LD 中的 author 字段，可能是字符串、对象或数组
+func jsonLDPersons(data interface{}, read *Readability) []Author {
+	var authors []Author
+	switch v := data.(type) {
+	case string:
+		authors = append(authors, splitByline(v, nil)...)
+	case []interface{}:
+		for _, item := range v {
+			authors = append(authors, jsonLDPersons(item, read)...)
+		}
+	case map[string]interface{}:
+		name, _ := v["name"].(string)
+		if len(ts(name)) == 0 {
+			break
+		}
+		a := Author{Name: normalizeSpace(ts(name))}
+		if u, ok := v["url"].(string); ok && len(u) > 0 {
+			a.URL = read.resolveURL(u)
+		}
+		a.Role, _ = v["jobTitle"].(string)
+		authors = append(authors, a)
+	}
+	return authors
+}
+
+// @type 可能是字符串或字符串数组
+func isJSONLDArticle(t interface{}) bool {
+	switch v := t.(type) {
+	case string:
+		return jsonLDArticleTypePattern.MatchString(v)
+	case []interface{}:
+		for _, item := range v {
+			if isJSONLDArticle(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func allMatch(s []string, p *regexp.Regexp) bool {
+	for _, v := range s {
+		if !p.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
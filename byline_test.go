@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParseTextByline(t *testing.T) {
+	paragraph := "<p>" + strings.Repeat("这是新闻正文中的一段文字，内容足够长以便获得候选分数，", 4) + "完。</p>"
+	page := `<html><head><meta charset="utf-8"><title>测试新闻标题示例</title></head><body>
+<article>
+<h1>测试新闻标题示例</h1>
+<div class="info">2018年10月16日 15:30 来源：人民网 作者：张三</div>
+<section class="content">` + strings.Repeat(paragraph, 5) + `
+<p>新华社北京10月16日电（记者 王五）这一段是正文，不应当被当作作者信息删除，因为它还包含了很多其他内容。</p>
+<p>记者 昨日走访了多家医院。</p>
+<p>（责任编辑：李四）</p>
+</section>
+</article>
+</body></html>`
+	article, err := New(Option{PageURL: "http://example.com/a.html"}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Byline != "张三" {
+		t.Errorf("Byline = %q，期望 张三", article.Byline)
+	}
+	if article.Source != "人民网" {
+		t.Errorf("Source = %q，期望 人民网", article.Source)
+	}
+	if article.Editor != "李四" {
+		t.Errorf("Editor = %q，期望 李四", article.Editor)
+	}
+	for _, line := range []string{"来源：人民网", "作者：张三", "责任编辑：李四"} {
+		if strings.Contains(article.Content, line) {
+			t.Errorf("正文中仍包含 %q", line)
+		}
+	}
+	for _, line := range []string{"记者 王五", "记者 昨日走访了多家医院。"} {
+		if !strings.Contains(article.Content, line) {
+			t.Errorf("含有 %q 的正文段落被误删", line)
+		}
+	}
+}
+
+func TestCheckTextByline(t *testing.T) {
+	cases := []struct {
+		text, byline, source, editor string
+		matched                      bool
+	}{
+		{"作者：张三", "张三", "", "", true},
+		{"来源: 新华网", "", "新华网", "", true},
+		{"责任编辑：李四", "", "", "李四", true},
+		{"记者 王五", "王五", "", "", true},
+		{"发布时间：2018-10-16 09:00 | 来源：人民网-人民日报", "", "人民网-人民日报", "", true},
+		{"（编辑：赵六）", "", "", "赵六", true},
+		{"本文作者：张三认为，这个问题需要从多个角度进行分析和讨论。", "", "", "", false},
+		{"这是普通的正文段落。", "", "", "", false},
+		{"记者 昨日走访了多家医院。", "", "", "", false},
+		{"记者 王五 昨日走访了多家医院。", "", "", "", false},
+	}
+	for _, c := range cases {
+		read := New(Option{})
+		matched := read.checkTextByline(newTestSelectionWithText("p", c.text))
+		if matched != c.matched ||
+			read.article.Byline != c.byline ||
+			read.article.Source != c.source ||
+			read.article.Editor != c.editor {
+			t.Errorf("%q: 得到 (%v, %q, %q, %q)", c.text, matched,
+				read.article.Byline, read.article.Source, read.article.Editor)
+		}
+	}
+}
+
+func TestSplitByline(t *testing.T) {
+	cases := map[string][]Author{
+		"By Jane Doe and John Smith":     {{Name: "Jane Doe"}, {Name: "John Smith"}},
+		"Jane Doe, John Smith & Bob Lee": {{Name: "Jane Doe"}, {Name: "John Smith"}, {Name: "Bob Lee"}},
+		"Jane Doe, Staff Writer":         {{Name: "Jane Doe", Role: "Staff Writer"}},
+		"张三 李四":                          {{Name: "张三"}, {Name: "李四"}},
+		"作者：张三、欧阳娜娜":                     {{Name: "张三"}, {Name: "欧阳娜娜"}},
+		"written by Ada Lovelace":        {{Name: "Ada Lovelace"}},
+		"  The Editorial Board  ":        {{Name: "The Editorial Board"}},
+		"Jane Doe, March 3, 2020":        {{Name: "Jane Doe"}},
+		"By Jane Doe | 2020-03-03 10:00": {{Name: "Jane Doe"}},
+		"张三和李四":                          {{Name: "张三"}, {Name: "李四"}},
+		"王小明与欧阳娜娜":                       {{Name: "王小明"}, {Name: "欧阳娜娜"}},
+		"和平":                             {{Name: "和平"}},
+		"user123, Jane Doe 2nd":          {{Name: "user123"}, {Name: "Jane Doe 2nd"}},
+		"Jane Doe 2020年3月3日":             {{Name: "Jane Doe"}},
+	}
+	for byline, expected := range cases {
+		got := splitByline(byline, nil)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("splitByline(%q) = %+v，期望 %+v", byline, got, expected)
+		}
+	}
+}
+
+func TestAuthorLinks(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<div class="byline">By ` +
+		`<a rel="author" href="/jane">Jane Doe</a>, <a href="/author/john-smith">John Smith</a>, ` +
+		`<a href="/topics/politics">Politics</a>, <a href="/2020/03/03/">March 3, 2020</a>, <a href="javascript:void(0)">Bob</a></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := New(Option{PageURL: "https://example.com/news/a.html"}).authorLinks(doc.Find(".byline"))
+	expected := map[string]string{
+		"Jane Doe":   "https://example.com/jane",
+		"John Smith": "https://example.com/author/john-smith",
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("authorLinks = %v，期望 %v", links, expected)
+	}
+}
+
+func TestParseAuthors(t *testing.T) {
+	paragraph := "<p>" + strings.Repeat("This is a reasonably long paragraph of article text — with commas, ", 4) + "and it ends here.</p>"
+	body := `<article>
+<div class="byline">By <a rel="author" href="/people/jane">Jane Doe</a> and <a href="https://example.com/people/john">John Smith</a></div>
+<section class="content">` + strings.Repeat(paragraph, 5) + `</section>
+</article>`
+	page := `<html><head><meta charset="utf-8"><title>An example article title here</title>%s</head><body>` + body + `</body></html>`
+
+	article, err := New(Option{PageURL: "https://example.com/news/a.html"}).Parse(fmt.Sprintf(page, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Author{
+		{Name: "Jane Doe", URL: "https://example.com/people/jane"},
+		{Name: "John Smith", URL: "https://example.com/people/john"},
+	}
+	if !reflect.DeepEqual(article.Authors, expected) {
+		t.Errorf("Authors = %+v，期望 %+v", article.Authors, expected)
+	}
+	if article.Byline != "By Jane Doe and John Smith" {
+		t.Errorf("Byline = %q", article.Byline)
+	}
+
+	jsonLD := `<script type="application/ld+json">{"@context":"https://schema.org","@graph":[
+{"@type":"WebSite","name":"Example"},
+{"@type":["NewsArticle"],"author":[{"@type":"Person","name":"Jane Doe","url":"/people/jane-doe","jobTitle":"Reporter"},{"@type":"Person","name":"John Smith"}]}
+]}</script>`
+	article, err = New(Option{PageURL: "https://example.com/news/a.html"}).Parse(fmt.Sprintf(page, jsonLD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []Author{
+		{Name: "Jane Doe", URL: "https://example.com/people/jane-doe", Role: "Reporter"},
+		{Name: "John Smith"},
+	}
+	if !reflect.DeepEqual(article.Authors, expected) {
+		t.Errorf("JSON-LD Authors = %+v，期望 %+v", article.Authors, expected)
+	}
+}
+
+func TestParseTextBylineMultipleAuthors(t *testing.T) {
+	read := New(Option{})
+	if !read.checkTextByline(newTestSelectionWithText("p", "记者 张三 李四 2018-10-16")) {
+		t.Fatal("未识别作者信息行")
+	}
+	expected := []Author{{Name: "张三", Role: "记者"}, {Name: "李四", Role: "记者"}}
+	if !reflect.DeepEqual(read.article.Authors, expected) {
+		t.Errorf("Authors = %+v，期望 %+v", read.article.Authors, expected)
+	}
+	if read.article.Byline != "张三、李四" {
+		t.Errorf("Byline = %q，期望 张三、李四", read.article.Byline)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	readability "github.com/naiba/go-readability"
+)
+
+// JSONL 输入中的一条记录
+type batchRecord struct {
+	URL  string `json:"url"`
+	HTML string `json:"html"`
+}
+
+type batchJob struct {
+	index int
+	// 目录模式下的文件路径，JSONL 模式下为空
+	path string
+	url  string
+	html string
+	// 读取或解码记录时的错误
+	err error
+}
+
+// 输出中的一条结果，Error 不为空时 Article 为空
+type batchResult struct {
+	Index      int                  `json:"index"`
+	Path       string               `json:"path,omitempty"`
+	URL        string               `json:"url,omitempty"`
+	Article    *readability.Article `json:"article,omitempty"`
+	Error      string               `json:"error,omitempty"`
+	DurationMs float64              `json:"durationMs"`
+}
+
+type batchConfig struct {
+	input   string
+	output  string
+	baseURL string
+	workers int
+	option  readability.Option
+}
+
+// readability batch [flags] <目录 | JSONL 文件 | ->
+func runBatch(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	c := new(batchConfig)
+	flags := flag.NewFlagSet("readability batch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "用法：readability batch [flags] <目录 | JSONL 文件 | ->")
+		fmt.Fprintln(stderr, "目录中的 .html/.htm 文件逐个提取；JSONL 每行为 {\"url\": ..., \"html\": ...}。")
+		fmt.Fprintln(stderr, "结果以 JSONL 输出，每行包含 index、path/url、article 或 error 以及耗时。")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&c.output, "output", "-", "结果输出文件，- 表示标准输出")
+	flags.StringVar(&c.baseURL, "base-url", "", "目录模式下文件的网页地址前缀，与文件相对路径拼接后作为 PageURL")
+	flags.IntVar(&c.workers, "workers", runtime.NumCPU(), "并发提取的数量")
+	addOptionFlags(flags, &c.option)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("需要指定一个输入目录或 JSONL 文件")
+	}
+	c.input = flags.Arg(0)
+	if c.workers <= 0 {
+		c.workers = 1
+	}
+
+	var w io.Writer = stdout
+	if c.output != "-" {
+		f, err := os.Create(c.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+
+	start := time.Now()
+	total, failed, err := batch(c, stdin, bw)
+	if flushErr := bw.Flush(); err == nil {
+		err = flushErr
+	}
+	fmt.Fprintf(stderr, "共处理 %d 条，失败 %d 条，用时 %s\n", total, failed, time.Since(start).Round(time.Millisecond))
+	return err
+}
+
+// 读取输入并以 c.workers 个协程提取，结果按完成顺序写入 w
+func batch(c *batchConfig, stdin io.Reader, w io.Writer) (total, failed int, err error) {
+	jobs := make(chan batchJob, c.workers)
+	results := make(chan batchResult, c.workers)
+
+	var readErr error
+	go func() {
+		defer close(jobs)
+		readErr = readJobs(c, stdin, jobs)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results <- extractJob(job, c.option)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for r := range results {
+		total++
+		if len(r.Error) > 0 {
+			failed++
+		}
+		// 写入失败后继续消费结果，避免协程阻塞
+		if err == nil {
+			err = enc.Encode(r)
+		}
+	}
+	if err == nil {
+		err = readErr
+	}
+	return total, failed, err
+}
+
+func readJobs(c *batchConfig, stdin io.Reader, jobs chan<- batchJob) error {
+	if c.input != "-" {
+		info, err := os.Stat(c.input)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return walkJobs(c.input, c.baseURL, jobs)
+		}
+		f, err := os.Open(c.input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stdin = f
+	}
+	return readJSONLJobs(stdin, jobs)
+}
+
+// 目录中的每个 HTML 文件为一条记录，文件内容由工作协程读取
+func walkJobs(dir, baseURL string, jobs chan<- batchJob) error {
+	index := 0
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if d.IsDir() || (ext != ".html" && ext != ".htm") {
+			return nil
+		}
+		job := batchJob{index: index, path: path}
+		if len(baseURL) > 0 {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			job.url = strings.TrimSuffix(baseURL, "/") + "/" + (&url.URL{Path: filepath.ToSlash(rel)}).EscapedPath()
+		}
+		jobs <- job
+		index++
+		return nil
+	})
+}
+
+// 每行一条 {url, html} 记录，单行可能有数 MB，因此不使用 bufio.Scanner
+func readJSONLJobs(r io.Reader, jobs chan<- batchJob) error {
+	br := bufio.NewReader(r)
+	for index := 0; ; {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record batchRecord
+			job := batchJob{index: index}
+			if job.err = json.Unmarshal(line, &record); job.err == nil {
+				job.url, job.html = record.URL, record.HTML
+			}
+			jobs <- job
+			index++
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractJob(job batchJob, o readability.Option) batchResult {
+	start := time.Now()
+	r := batchResult{Index: job.index, Path: job.path, URL: job.url}
+	err := job.err
+	if err == nil && len(job.path) > 0 {
+		var b []byte
+		b, err = os.ReadFile(job.path)
+		job.html = string(b)
+	}
+	if err == nil {
+		o.PageURL = job.url
+		r.Article, err = readability.New(o).Parse(job.html)
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	r.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	return r
+}
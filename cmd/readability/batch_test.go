@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func readResults(t *testing.T, b []byte) []batchResult {
+	var results []batchResult
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var r batchResult
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("%v: %s", err, scanner.Text())
+		}
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	return results
+}
+
+func TestBatchDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.html", filepath.Join("sub", "b.htm"), "empty.html", "notes.txt"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := testPage
+		if name == "empty.html" {
+			content = ""
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"batch", "--workers", "2", "--base-url", "http://example.com/archive/", dir}, nil, &stdout, &stderr); err != nil {
+		t.Fatal(err, stderr.String())
+	}
+	results := readResults(t, stdout.Bytes())
+	if len(results) != 3 {
+		t.Fatalf("得到 %d 条结果，期望 3 条：\n%s", len(results), stdout.String())
+	}
+	byURL := make(map[string]batchResult)
+	for _, r := range results {
+		byURL[r.URL] = r
+	}
+	if r := byURL["http://example.com/archive/sub/b.htm"]; r.Article == nil || r.Article.Title != "命令行测试文章" {
+		t.Errorf("sub/b.htm 提取失败：%+v", r)
+	}
+	if r := byURL["http://example.com/archive/empty.html"]; r.Article != nil || len(r.Error) == 0 {
+		t.Errorf("空文件应返回错误：%+v", r)
+	}
+	if !strings.Contains(stderr.String(), "共处理 3 条，失败 1 条") {
+		t.Errorf("统计信息：%s", stderr.String())
+	}
+}
+
+func TestBatchJSONL(t *testing.T) {
+	var input bytes.Buffer
+	enc := json.NewEncoder(&input)
+	for i := 0; i < 5; i++ {
+		if err := enc.Encode(batchRecord{URL: "http://example.com/" + string(rune('a'+i)) + ".html", HTML: testPage}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	input.WriteString("\n{不是 JSON}\n")
+
+	output := filepath.Join(t.TempDir(), "out.jsonl")
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"batch", "--workers", "3", "--output", output, "-"}, &input, &stdout, &stderr); err != nil {
+		t.Fatal(err, stderr.String())
+	}
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := readResults(t, b)
+	if len(results) != 6 {
+		t.Fatalf("得到 %d 条结果，期望 6 条", len(results))
+	}
+	for i, r := range results[:5] {
+		if r.Index != i || r.Article == nil || r.Article.URL != r.URL || !strings.Contains(r.Article.Content, `href="http://example.com/more"`) {
+			t.Errorf("第 %d 条结果有误：%+v", i, r)
+		}
+	}
+	if r := results[5]; r.Index != 5 || len(r.Error) == 0 {
+		t.Errorf("无效的记录应返回错误：%+v", r)
+	}
+}
+
+func TestBatchMissingInput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"batch", filepath.Join(t.TempDir(), "missing")}, nil, &stdout, &stderr); err == nil {
+		t.Error("期望返回错误")
+	}
+	if err := run([]string{"batch"}, nil, &stdout, &stderr); err == nil {
+		t.Error("期望返回错误")
+	}
+}
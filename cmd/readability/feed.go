@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	readability "github.com/naiba/go-readability"
+)
+
+// readability feed [flags] <feed URL | 文件 | ->
+func runFeed(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var o readability.FeedOption
+	var feedURL string
+	var timeout time.Duration
+	flags := flag.NewFlagSet("readability feed", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "用法：readability feed [flags] <feed URL | 文件 | ->")
+		fmt.Fprintln(stderr, "获取 RSS/Atom 中每个条目的网页并提取正文，输出带全文的 feed。")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&feedURL, "url", "", "feed 地址，用于转换相对的条目链接；输入为 URL 时默认为最终地址")
+	flags.DurationVar(&timeout, "timeout", 5*time.Minute, "整个转换的超时时间")
+	flags.IntVar(&o.Workers, "workers", 4, "并发获取条目的数量")
+	flags.IntVar(&o.MaxItems, "max-items", 0, "最多转换的条目数，其余条目保持原样，0 表示不限制")
+	addOptionFlags(flags, &o.Option)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("需要指定一个 feed")
+	}
+	input := flags.Arg(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	proxy := readability.NewFeedProxy(o)
+	var out []byte
+	var err error
+	if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
+		out, err = proxy.ConvertURL(ctx, input)
+	} else {
+		var r io.Reader = stdin
+		if input != "-" {
+			f, err := os.Open(input)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		var feed []byte
+		if feed, err = io.ReadAll(r); err == nil {
+			out, err = proxy.Convert(ctx, feed, feedURL)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(out)
+	return err
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRunFeed(t *testing.T) {
+	feed := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel><title>测试</title>
+<item><title>命令行测试文章</title><link>/post.html</link><description>摘要</description></item>
+</channel></rss>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprint(w, feed)
+		case "/post.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, testPage)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	for _, args := range [][]string{
+		{"feed", "--char-threshold", "100", srv.URL + "/feed.xml"},
+		{"feed", "--char-threshold", "100", "--url", srv.URL + "/feed.xml", "-"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if err := run(args, strings.NewReader(feed), &stdout, &stderr); err != nil {
+			t.Fatalf("%v: %v %s", args, err, stderr.String())
+		}
+		out := stdout.String()
+		if !strings.Contains(out, "<content:encoded><![CDATA[") || !strings.Contains(out, "这是一段用于测试命令行工具的正文内容") {
+			t.Errorf("%v: 输出中没有全文：\n%s", args, out)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"feed", "-"}, strings.NewReader("<html></html>"), &stdout, &stderr); err == nil {
+		t.Error("非 feed 输入应返回错误")
+	}
+}
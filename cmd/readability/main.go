@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+// readability 从命令行提取网页正文，用于在不编写 Go 代码的情况下复现提取问题。
+//
+//	readability [flags] [文件 | URL | -]
+//	readability batch [flags] <目录 | JSONL 文件 | ->
+//	readability serve [flags]
+//	readability feed [flags] <feed URL | 文件 | ->
+//
+// 不指定输入或输入为 "-" 时从标准输入读取 HTML。batch 子命令批量提取目录中的
+// HTML 文件或 JSONL 中的记录，结果以 JSONL 输出。serve 子命令以 HTTP 服务的
+// 形式提供提取接口。feed 子命令把 RSS/Atom 转换为带全文的 feed。
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	readability "github.com/naiba/go-readability"
+)
+
+const (
+	formatHTML     = "html"
+	formatText     = "text"
+	formatMarkdown = "markdown"
+	formatJSON     = "json"
+	formatCSV      = "csv"
+)
+
+type config struct {
+	input   string
+	pageURL string
+	format  string
+	timeout time.Duration
+	option  readability.Option
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "readability:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "batch":
+			return runBatch(args[1:], stdin, stdout, stderr)
+		case "serve":
+			return runServe(args[1:], stderr)
+		case "feed":
+			return runFeed(args[1:], stdin, stdout, stderr)
+		}
+	}
+	c, err := parseFlags(args, stderr)
+	if err != nil {
+		return err
+	}
+	article, err := extract(c, stdin)
+	if err != nil {
+		return err
+	}
+	return writeArticle(stdout, article, c.format)
+}
+
+func parseFlags(args []string, stderr io.Writer) (*config, error) {
+	c := new(config)
+	fs := flag.NewFlagSet("readability", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "用法：readability [flags] [文件 | URL | -]")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&c.pageURL, "url", "", "网页地址，用于转换相对链接；输入为 URL 时默认为最终地址")
+	fs.StringVar(&c.format, "format", formatHTML, "输出格式：html、text、markdown、json，或 csv（只输出正文中的数据表格）")
+	fs.DurationVar(&c.timeout, "timeout", 30*time.Second, "获取 URL 的超时时间")
+	addOptionFlags(fs, &c.option)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	switch c.format {
+	case formatHTML, formatText, formatMarkdown, formatJSON, formatCSV:
+	default:
+		return nil, fmt.Errorf("不支持的输出格式：%s", c.format)
+	}
+	switch fs.NArg() {
+	case 0:
+		c.input = "-"
+	case 1:
+		c.input = fs.Arg(0)
+	default:
+		fs.Usage()
+		return nil, errors.New("只能指定一个输入")
+	}
+	return c, nil
+}
+
+// 与 readability.Option 对应的参数
+func addOptionFlags(fs *flag.FlagSet, o *readability.Option) {
+	fs.IntVar(&o.CharThreshold, "char-threshold", 0, "正文最少字符数，不足时放宽条件重新提取，0 表示使用默认值")
+	fs.IntVar(&o.NbTopCandidates, "top-candidates", 0, "参与比较的候选节点数量，0 表示使用默认值")
+	fs.IntVar(&o.MaxNodeNum, "max-nodes", 0, "最多解析的节点数，0 表示不限制")
+	fs.IntVar(&o.MaxPages, "max-pages", 0, "最多合并的分页数，0 表示使用默认值")
+	fs.BoolVar(&o.Debug, "debug", false, "输出调试日志")
+	fs.BoolVar(&o.NormalizeHeadings, "normalize-headings", false, "调整正文中标题的级别，使最高一级为 h2")
+	fs.BoolVar(&o.ExtractComments, "comments", false, "提取评论区中的评论，在 json 格式中输出")
+	fs.Var(sanitizeFlag{o}, "sanitize", "按默认白名单净化正文，去除脚本、事件属性与 javascript: 等地址")
+}
+
+// --sanitize 为 true 时使用默认的净化白名单
+type sanitizeFlag struct {
+	o *readability.Option
+}
+
+func (f sanitizeFlag) String() string {
+	if f.o == nil || f.o.Sanitizer == nil {
+		return "false"
+	}
+	return "true"
+}
+
+func (f sanitizeFlag) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	f.o.Sanitizer = nil
+	if v {
+		f.o.Sanitizer = readability.DefaultSanitizePolicy()
+	}
+	return nil
+}
+
+func (f sanitizeFlag) IsBoolFlag() bool {
+	return true
+}
+
+func extract(c *config, stdin io.Reader) (*readability.Article, error) {
+	if strings.HasPrefix(c.input, "http://") || strings.HasPrefix(c.input, "https://") {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+		fetcher := &readability.HTTPFetcher{Timeout: c.timeout}
+		o := c.option
+		o.Timeout = c.timeout
+		o.Fetcher = fetcher
+		if len(c.pageURL) == 0 {
+			return readability.ParseURL(ctx, c.input, o)
+		}
+		// 指定了 --url 时以其作为相对链接的基准地址
+		h, err := fetcher.Fetch(ctx, c.input)
+		if err != nil {
+			return nil, err
+		}
+		o.PageURL = c.pageURL
+		return readability.New(o).ParseContext(ctx, h)
+	}
+
+	var r io.Reader = stdin
+	if c.input != "-" {
+		f, err := os.Open(c.input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	o := c.option
+	o.PageURL = c.pageURL
+	return readability.New(o).Parse(string(b))
+}
+
+func writeArticle(w io.Writer, article *readability.Article, format string) error {
+	var err error
+	switch format {
+	case formatText:
+		_, err = fmt.Fprintln(w, article.TextContent)
+	case formatMarkdown:
+		var md string
+		if md, err = toMarkdown(article.Content); err == nil {
+			if len(article.Title) > 0 {
+				md = "# " + article.Title + "\n\n" + md
+			}
+			_, err = fmt.Fprintln(w, md)
+		}
+	case formatCSV:
+		// 每个表格之间空一行
+		for i := range article.Tables {
+			if i > 0 {
+				if _, err = fmt.Fprintln(w); err != nil {
+					break
+				}
+			}
+			if err = article.Tables[i].WriteCSV(w); err != nil {
+				break
+			}
+		}
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		err = enc.Encode(article)
+	default:
+		_, err = fmt.Fprintln(w, article.Content)
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	readability "github.com/naiba/go-readability"
+)
+
+var testPage = `<html><head><title>命令行测试文章</title></head><body>
+<div class="content">` + strings.Repeat(`<p>这是一段用于测试命令行工具的正文内容，包含逗号，以及足够的长度让它成为候选节点。<a href="/more">更多</a></p>`, 5) + `</div>
+</body></html>`
+
+func TestRun(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "page.html")
+	if err := os.WriteFile(file, []byte(testPage), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		args     []string
+		contains string
+	}{
+		{[]string{file}, `<div id="readability-page-1"`},
+		{[]string{"--format", "text", file}, "这是一段用于测试命令行工具的正文内容"},
+		{[]string{"--format", "markdown", "--url", "http://example.com/a/b.html", file}, "# 命令行测试文章\n\n这是一段"},
+		{[]string{"--format", "markdown", "--url", "http://example.com/a/b.html", file}, "[更多](http://example.com/more)"},
+		{[]string{"--format", "json", "--url", "http://example.com/a/b.html", "-"}, `"url": "http://example.com/a/b.html"`},
+		{[]string{"--sanitize", "--url", "http://example.com/a/b.html", file}, `<a href="http://example.com/more">更多</a>`},
+	}
+	for _, c := range cases {
+		var stdout, stderr bytes.Buffer
+		if err := run(c.args, strings.NewReader(testPage), &stdout, &stderr); err != nil {
+			t.Fatalf("%v: %v %s", c.args, err, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), c.contains) {
+			t.Errorf("%v: 输出中没有 %q：\n%s", c.args, c.contains, stdout.String())
+		}
+	}
+}
+
+func TestRunCSV(t *testing.T) {
+	page := strings.Replace(testPage, `</div>`, `<table><caption>数据</caption><tr><th>名称</th><th>数量</th></tr><tr><td>苹果</td><td>1,000</td></tr></table>
+<table><thead><tr><th>a</th></tr></thead><tbody><tr><td>1</td></tr></tbody></table></div>`, 1)
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"--format", "csv", "-"}, strings.NewReader(page), &stdout, &stderr); err != nil {
+		t.Fatal(err, stderr.String())
+	}
+	if want := "名称,数量\n苹果,\"1,000\"\n\na\n1\n"; stdout.String() != want {
+		t.Errorf("输出 %q，期望 %q", stdout.String(), want)
+	}
+}
+
+func TestRunURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, testPage)
+	}))
+	defer srv.Close()
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"--format", "json", "--char-threshold", "100", srv.URL + "/post.html"}, nil, &stdout, &stderr); err != nil {
+		t.Fatal(err, stderr.String())
+	}
+	var article readability.Article
+	if err := json.Unmarshal(stdout.Bytes(), &article); err != nil {
+		t.Fatal(err)
+	}
+	if article.Title != "命令行测试文章" || article.URL != srv.URL+"/post.html" {
+		t.Errorf("标题 %q，地址 %q", article.Title, article.URL)
+	}
+	if !strings.Contains(article.Content, `href="`+srv.URL+`/more"`) {
+		t.Errorf("相对链接未按最终地址转换：%s", article.Content)
+	}
+}
+
+func TestRunInvalidFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"--format", "pdf"},
+		{"a.html", "b.html"},
+		{"--no-such-flag"},
+		{filepath.Join(t.TempDir(), "missing.html")},
+	} {
+		var stdout, stderr bytes.Buffer
+		if err := run(args, strings.NewReader(""), &stdout, &stderr); err == nil {
+			t.Errorf("%v: 期望返回错误", args)
+		}
+	}
+}
+
+func TestRunServeInvalidFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"serve", "extra"},
+		{"serve", "--no-such-flag"},
+		{"serve", "--addr", "256.0.0.1:bad"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if err := run(args, nil, &stdout, &stderr); err == nil {
+			t.Errorf("%v: 期望返回错误", args)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	spacesPattern = regexp.MustCompile(`\s+`)
+	// 文本中会被当作 Markdown 语法的字符
+	markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+)
+
+// 作为块级内容输出的元素，其余元素按行内内容处理
+var markdownBlocks = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "dd": true,
+	"div": true, "dl": true, "dt": true, "figcaption": true, "figure": true,
+	"footer": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hr": true, "li": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "table": true, "ul": true,
+}
+
+// 将提取出的正文 HTML 转换为 Markdown
+func toMarkdown(s string) (string, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(s), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(markdownBlock(nodes)), nil
+}
+
+func children(n *html.Node) []*html.Node {
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, c)
+	}
+	return nodes
+}
+
+// 依次输出块级元素，相邻的行内内容合并为一个段落
+func markdownBlock(nodes []*html.Node) string {
+	var blocks []string
+	var inline strings.Builder
+	flush := func() {
+		if p := strings.TrimSpace(inline.String()); len(p) > 0 {
+			blocks = append(blocks, p)
+		}
+		inline.Reset()
+	}
+	for _, n := range nodes {
+		if n.Type != html.ElementNode || !markdownBlocks[n.Data] {
+			inline.WriteString(markdownInline(n))
+			continue
+		}
+		flush()
+		if b := markdownElement(n); len(strings.TrimSpace(b)) > 0 {
+			blocks = append(blocks, b)
+		}
+	}
+	flush()
+	return strings.Join(blocks, "\n\n")
+}
+
+func markdownElement(n *html.Node) string {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ := strconv.Atoi(n.Data[1:])
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(markdownInlineChildren(n))
+	case "p", "dt", "figcaption":
+		return strings.TrimSpace(markdownInlineChildren(n))
+	case "hr":
+		return "---"
+	case "pre":
+		return markdownPre(n)
+	case "blockquote":
+		return prefixLines(markdownBlock(children(n)), "> ", "> ")
+	case "ul", "ol":
+		return markdownList(n)
+	case "table":
+		return markdownTable(n)
+	case "dd":
+		return prefixLines(markdownBlock(children(n)), ": ", "  ")
+	}
+	return markdownBlock(children(n))
+}
+
+func markdownPre(n *html.Node) string {
+	lang := ""
+	code := n
+	if c := n.FirstChild; c != nil && c.NextSibling == nil && c.Type == html.ElementNode && c.Data == "code" {
+		code = c
+		for _, class := range strings.Fields(attr(c, "class")) {
+			if strings.HasPrefix(class, "language-") {
+				lang = strings.TrimPrefix(class, "language-")
+			}
+		}
+	}
+	fence := "```"
+	text := strings.TrimRight(textContent(code), "\n")
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + text + "\n" + fence
+}
+
+func markdownList(n *html.Node) string {
+	var items []string
+	i := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		i = start
+	}
+	for _, li := range children(n) {
+		if li.Type != html.ElementNode || li.Data != "li" {
+			continue
+		}
+		marker := "- "
+		if n.Data == "ol" {
+			marker = strconv.Itoa(i) + ". "
+			i++
+		}
+		items = append(items, prefixLines(markdownBlock(children(li)), marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+func markdownTable(n *html.Node) string {
+	var rows [][]string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "tr":
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						text := strings.TrimSpace(markdownInlineChildren(cell))
+						row = append(row, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				rows = append(rows, row)
+			case "table":
+				// 嵌套表格按段落输出
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	if cols == 0 {
+		return ""
+	}
+	var lines []string
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", cols))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func markdownInlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(markdownInline(c))
+	}
+	return b.String()
+}
+
+func markdownInline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return markdownEscaper.Replace(spacesPattern.ReplaceAllString(n.Data, " "))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	switch n.Data {
+	case "br":
+		return "  \n"
+	case "img":
+		src := attr(n, "src")
+		if len(src) == 0 {
+			return ""
+		}
+		return "![" + markdownEscaper.Replace(attr(n, "alt")) + "](" + src + ")"
+	case "script", "style", "noscript":
+		return ""
+	}
+	inner := markdownInlineChildren(n)
+	if len(strings.TrimSpace(inner)) == 0 {
+		return inner
+	}
+	switch n.Data {
+	case "a":
+		if href := attr(n, "href"); len(href) > 0 && !strings.HasPrefix(href, "javascript:") {
+			return "[" + strings.TrimSpace(inner) + "](" + href + ")"
+		}
+	case "strong", "b":
+		return wrapInline(inner, "**")
+	case "em", "i":
+		return wrapInline(inner, "*")
+	case "del", "s", "strike":
+		return wrapInline(inner, "~~")
+	case "code", "kbd", "samp":
+		code := textContent(n)
+		fence := "`"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return fence + code + fence
+	}
+	if markdownBlocks[n.Data] {
+		// 行内元素中的块级元素，如 <a><div>...</div></a>
+		return " " + strings.TrimSpace(markdownBlock(children(n))) + " "
+	}
+	return inner
+}
+
+// 标记放在首尾空白之内，否则不会被识别
+func wrapInline(s, mark string) string {
+	trimmed := strings.TrimSpace(s)
+	start := strings.Index(s, trimmed)
+	return s[:start] + mark + trimmed + mark + s[start+len(trimmed):]
+}
+
+// 第一行加 first 前缀，其余非空行加 rest 前缀
+func prefixLines(s, first, rest string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = first + line
+		case len(line) > 0:
+			lines[i] = rest + line
+		case strings.TrimSpace(rest) != "":
+			lines[i] = strings.TrimRight(rest, " ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
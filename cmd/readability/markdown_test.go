@@ -0,0 +1,35 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package main
+
+import "testing"
+
+func TestToMarkdown(t *testing.T) {
+	cases := map[string]string{
+		`<div><h2>标题</h2><p>第一段 <b>加粗</b>、<em>斜体</em>和<a href="http://a/b">链接</a>。</p></div>`: "## 标题\n\n第一段 **加粗**、*斜体*和[链接](http://a/b)。",
+		`<p>a_b *c*</p>`:  `a\_b \*c\*`,
+		`<p>行一<br>行二</p>`: "行一  \n行二",
+		`<ul><li>甲</li><li>乙<ol><li>子项</li></ol></li></ul>`: "- 甲\n- 乙\n\n  1. 子项",
+		`<blockquote><p>引用一</p><p>引用二</p></blockquote>`:     "> 引用一\n>\n> 引用二",
+		`<pre><code class="language-go">func main() {
+	fmt.Println("*")
+}
+</code></pre>`: "```go\nfunc main() {\n\tfmt.Println(\"*\")\n}\n```",
+		`<p>用 <code>go test</code> 运行</p>`:                                             "用 `go test` 运行",
+		`<table><tr><th>名称</th><th>值</th></tr><tr><td>a|b</td><td>1</td></tr></table>`: "| 名称 | 值 |\n| --- | --- |\n| a\\|b | 1 |",
+		`<div><img src="http://a/1.png" alt="图"><p>说明</p></div>`:                       "![图](http://a/1.png)\n\n说明",
+		`<hr><p>  </p>`: "---",
+	}
+	for source, expected := range cases {
+		md, err := toMarkdown(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if md != expected {
+			t.Errorf("%s\n得到：\n%s\n期望：\n%s", source, md, expected)
+		}
+	}
+}
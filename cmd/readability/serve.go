@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	readability "github.com/naiba/go-readability"
+)
+
+// readability serve [flags]
+func runServe(args []string, stderr io.Writer) error {
+	var addr string
+	var o readability.ServerOption
+	flags := flag.NewFlagSet("readability serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "用法：readability serve [flags]")
+		fmt.Fprintln(stderr, "提供 POST/GET /extract、GET /healthz 与 GET /metrics。")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&addr, "addr", ":8080", "监听地址")
+	flags.Int64Var(&o.MaxRequestSize, "max-request-size", 10<<20, "请求体的最大字节数")
+	flags.DurationVar(&o.Timeout, "timeout", 30*time.Second, "单个请求的超时时间")
+	allowPrivate := flags.Bool("allow-private", false, "允许获取回环、内网等非公网地址的网页")
+	addOptionFlags(flags, &o.Option)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return errors.New("serve 不接受输入参数")
+	}
+	o.Option.Fetcher = &readability.HTTPFetcher{Timeout: o.Timeout}
+	if *allowPrivate {
+		o.AllowIP = func(net.IP) bool { return true }
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           readability.NewServer(o),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	fmt.Fprintln(stderr, "readability: 监听", addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	// 收到退出信号后等待处理中的请求完成
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), o.Timeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
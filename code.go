@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 代码块：语法高亮把代码拆成大量带 class 的 span，有的还用表格排列行号，清理 class 后
+// 只剩难以阅读的片段，表格还会被当作布局展开。评分之前把高亮的代码块整理为
+// <pre><code class="language-x">代码</code></pre>，语言取自高亮器留下的 class。
+
+var (
+	// 高亮器的 class
+	highlighterPattern = regexp.MustCompile(`(?i)(?:^|\s)(?:highlight\S*|hljs|chroma|prism\S*|prettyprint(?:ed)?|syntax\S*|sourceCode|codehilite|brush:|rouge\S*|code-block|language-\S+|lang-\S+)(?:\s|$)`)
+	// 高亮器给代码中的片段加的 class：highlight.js、Prism、GitHub、CodeMirror 等
+	codeTokenPattern = regexp.MustCompile(`(?i)(?:^|\s)(?:hljs-\S+|token|tok-\S+|pl-\S+|cm-\S+)(?:\s|$)`)
+	// 语言：language-go、lang-go、highlight-source-go、brush: go
+	codeLanguagePattern = regexp.MustCompile(`(?i)(?:^|\s)(?:language-|lang-|highlight-(?:source-|text-)?|brush:\s*)([a-z0-9][\w+#.-]*)`)
+	// 行号等不属于代码的部分
+	codeGutterPattern = regexp.MustCompile(`(?i)(?:^|\s)(?:linenos?|lineno|ln|gutter|rouge-gutter|line-?numbers?(?:-rows)?|hljs-ln-numbers|blob-num|line-number)(?:\s|$)`)
+	// 代码中按行排列的元素
+	codeLineTags = map[string]bool{"div": true, "p": true, "li": true, "tr": true}
+	// SyntaxHighlighter 中不表示语言的 class
+	syntaxHighlighterClasses = map[string]bool{"syntaxhighlighter": true, "nogutter": true, "collapsed": true, "printing": true, "ie": true}
+	// 不表示语言的 class 值
+	codeLanguageIgnored = map[string]bool{"none": true, "nohighlight": true, "plaintext": true, "rouge": true}
+)
+
+// 整理高亮的代码块，需在展开布局表格与处理 <br> 之前调用
+func (read *Readability) normalizeCodeBlocks() {
+	// SyntaxHighlighter 等以表格排列行号与代码，代码中没有 <pre>
+	read.dom.Find("div.syntaxhighlighter").Each(func(i int, s *goquery.Selection) {
+		code := s.Find("td.code").First()
+		if code.Length() == 0 {
+			return
+		}
+		// 语言是单独的 class，如 class="syntaxhighlighter js"
+		lang := codeLanguage(s)
+		for _, cls := range strings.Fields(s.AttrOr("class", "")) {
+			if len(lang) == 0 && !syntaxHighlighterClasses[cls] {
+				lang = strings.ToLower(cls)
+			}
+		}
+		replaceNode(s.Get(0), newCodeBlock(codeText(code.Get(0)), lang))
+	})
+	read.dom.Find("pre").Each(func(i int, pre *goquery.Selection) {
+		n := pre.Get(0)
+		if n.Parent == nil || pre.ParentsFiltered("pre").Length() > 0 {
+			return
+		}
+		// 行号所在的 <pre>，随表格一起删除
+		if pre.ParentsFiltered("td").FilterFunction(func(i int, td *goquery.Selection) bool {
+			return codeGutterPattern.MatchString(td.AttrOr("class", ""))
+		}).Length() > 0 {
+			return
+		}
+		if !isHighlighted(pre) {
+			return
+		}
+		text := codeText(n)
+		lang := codeLanguage(pre)
+		// 以表格排列行号时替换整个表格
+		at := n
+		if table := pre.Closest("table"); table.Length() > 0 && table.Find("pre").Length() <= 2 &&
+			table.Find("td").FilterFunction(func(i int, td *goquery.Selection) bool {
+				return codeGutterPattern.MatchString(td.AttrOr("class", ""))
+			}).Length() > 0 {
+			at = table.Get(0)
+		}
+		// 只包着这个代码块的高亮器容器一并替换，如 <div class="highlight">
+		for p := at.Parent; p != nil && p.Type == html.ElementNode && (p.Data == "div" || p.Data == "figure") &&
+			onlyElementChild(p) == at && highlighterPattern.MatchString(getAttr(p, "class")); p = p.Parent {
+			at = p
+		}
+		replaceNode(at, newCodeBlock(text, lang))
+	})
+}
+
+// 代码块或其容器带有高亮器的 class，或者代码中有高亮器加的 class，
+// 只是带有其他 class 的元素（如 <a class="x">）的 <pre> 不算
+func isHighlighted(pre *goquery.Selection) bool {
+	if pre.Find("[class]").FilterFunction(func(i int, s *goquery.Selection) bool {
+		class := s.AttrOr("class", "")
+		return highlighterPattern.MatchString(class) || codeTokenPattern.MatchString(class)
+	}).Length() > 0 {
+		return true
+	}
+	for s, i := pre, 0; s.Length() > 0 && i < 4; s, i = s.Parent(), i+1 {
+		if highlighterPattern.MatchString(s.AttrOr("class", "")) || len(s.AttrOr("data-lang", "")) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// 代码的语言，依次查找 <code>、<pre> 与外层容器的 class 与 data-lang
+func codeLanguage(s *goquery.Selection) string {
+	candidates := []*goquery.Selection{s.Find("code").First(), s}
+	for p, i := s.Parent(), 0; p.Length() > 0 && i < 3; p, i = p.Parent(), i+1 {
+		candidates = append(candidates, p)
+	}
+	for _, c := range candidates {
+		if c.Length() == 0 {
+			continue
+		}
+		for _, key := range []string{"data-lang", "data-language"} {
+			if lang := strings.ToLower(ts(c.AttrOr(key, ""))); len(lang) > 0 && !codeLanguageIgnored[lang] {
+				return lang
+			}
+		}
+		for _, m := range codeLanguagePattern.FindAllStringSubmatch(c.AttrOr("class", ""), -1) {
+			if lang := strings.ToLower(strings.TrimRight(m[1], ";")); !codeLanguageIgnored[lang] {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// 代码的文字：<br> 与按行排列的元素换行，跳过行号
+func codeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.Type == html.TextNode:
+				b.WriteString(c.Data)
+			case c.Type != html.ElementNode:
+			case c.Data == "br":
+				b.WriteByte('\n')
+			case codeGutterPattern.MatchString(getAttr(c, "class")):
+			case codeLineTags[c.Data]:
+				if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+					b.WriteByte('\n')
+				}
+				walk(c)
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return strings.TrimRight(strings.TrimLeft(b.String(), "\n"), " \t\n")
+}
+
+func newCodeBlock(text, lang string) *html.Node {
+	pre := &html.Node{Type: html.ElementNode, Data: "pre", DataAtom: atom.Pre}
+	code := &html.Node{Type: html.ElementNode, Data: "code", DataAtom: atom.Code}
+	if len(lang) > 0 {
+		code.Attr = []html.Attribute{{Key: "class", Val: "language-" + lang}}
+	}
+	code.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+	pre.AppendChild(code)
+	return pre
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的代码。</p>`
+	page := `<html><body><article>` + strings.Repeat(paragraph, 3) +
+		// highlight.js
+		`<pre><code class="hljs language-go"><span class="hljs-keyword">func</span> <span class="hljs-title">main</span>() {
+	fmt.Println(<span class="hljs-string">"hi"</span>)
+}</code></pre>` + paragraph +
+		// GitHub
+		`<div class="highlight highlight-source-python"><pre><span class="pl-k">def</span> <span class="pl-en">f</span>():
+    <span class="pl-k">return</span> <span class="pl-c1">1</span></pre></div>` + paragraph +
+		// Jekyll/Rouge 带行号
+		`<div class="language-ruby highlighter-rouge"><div class="highlight"><pre class="highlight"><code><table class="rouge-table"><tbody><tr><td class="rouge-gutter gl"><pre class="lineno">1
+2
+</pre></td><td class="rouge-code"><pre><span class="nb">puts</span> <span class="mi">1</span>
+<span class="nb">puts</span> <span class="mi">2</span>
+</pre></td></tr></tbody></table></code></pre></div></div>` + paragraph +
+		// SyntaxHighlighter
+		`<div class="syntaxhighlighter  js"><table border="0" cellpadding="0" cellspacing="0"><tbody><tr><td class="gutter"><div class="line number1 index0 alt2">1</div><div class="line number2 index1 alt1">2</div></td><td class="code"><div class="container"><div class="line number1 index0 alt2"><code class="js keyword">var</code> <code class="js plain">a = 1;</code></div><div class="line number2 index1 alt1"><code class="js plain">&nbsp;&nbsp;a++;</code></div></div></td></tr></tbody></table></div>` + paragraph +
+		// Prism 行号
+		`<pre class="language-bash line-numbers"><code class="language-bash">echo <span class="token string">"a"</span><span aria-hidden="true" class="line-numbers-rows"><span></span></span></code></pre>` +
+		// 没有高亮的代码块保持原样
+		`<pre>  plain
+    text</pre>` +
+		// 只是带有 class 的链接不是高亮，保留链接
+		`<pre>参见 <a class="ref" href="https://example.com/doc">文档</a></pre>` +
+		strings.Repeat(paragraph, 3) + `</article></body></html>`
+	article, err := New(Option{}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"<pre><code class=\"language-go\">func main() {\n\tfmt.Println(&#34;hi&#34;)\n}</code></pre>",
+		"<pre><code class=\"language-python\">def f():\n    return 1</code></pre>",
+		"<pre><code class=\"language-ruby\">puts 1\nputs 2</code></pre>",
+		"<pre><code class=\"language-js\">var a = 1;\n  a++;</code></pre>",
+		"<pre><code class=\"language-bash\">echo &#34;a&#34;</code></pre>",
+		"<pre>  plain\n    text</pre>",
+		`<pre>参见 <a href="https://example.com/doc">文档</a></pre>`,
+	} {
+		if !strings.Contains(article.Content, s) {
+			t.Errorf("正文中没有 %q", s)
+		}
+	}
+	if strings.Contains(article.Content, "<table") {
+		t.Errorf("行号表格应被删除")
+	}
+	if t.Failed() {
+		t.Log(article.Content)
+	}
+}
+
+func TestCodeLanguage(t *testing.T) {
+	for h, want := range map[string]string{
+		`<pre class="brush: csharp; gutter: false">x</pre>`:           "csharp",
+		`<pre><code class="lang-C++">x</code></pre>`:                  "c++",
+		`<div class="highlight-text-html-basic"><pre>x</pre></div>`:   "html-basic",
+		`<pre data-lang="Rust"><code class="hljs">x</code></pre>`:     "rust",
+		`<pre><code class="nohighlight language-none">x</code></pre>`: "",
+	} {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(h))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := codeLanguage(doc.Find("pre").First()); got != want {
+			t.Errorf("codeLanguage(%s) = %q，期望 %q", h, got, want)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// 评论：正文提取会按 comment、disqus 等 class 删除评论区，Option.ExtractComments 为 true 时
+// 在预处理之前单独从原始文档中提取评论，不影响正文。
+//
+// 评论条目按 class、id 与 schema.org 的 Comment 识别，只在评论区中查找。WordPress 等把一条评论拆成外层的
+// <li class="comment"> 与其中的 <article class="comment-body">，外层本身没有文字，
+// 这样的外层与其中第一个条目视为同一条评论，其余条目为回复。
+
+var (
+	// 评论区的 class/id
+	commentRegionPattern = regexp.MustCompile(`(?i)^(?:comments?|comment-?list|comments?[-_](?:area|section|wrapper|container|box)|replies|discussion|disqus_thread|respond)$`)
+	// 评论条目的 class
+	commentItemPattern = regexp.MustCompile(`(?i)^(?:comment|comment[-_]?item|reply[-_]?item)$`)
+	// 评论条目的 id，如 comment-12、div-comment-12
+	commentIDPattern     = regexp.MustCompile(`(?i)^(?:(?:li|div)-)?(?:comment|reply)[-_]?\d+$`)
+	commentAuthorPattern = regexp.MustCompile(`(?i)(?:^|[-_\s])(?:author|user(?:name)?|nick(?:name)?|fn)(?:[-_\s]|$)`)
+	commentDatePattern   = regexp.MustCompile(`(?i)(?:^|[-_\s])(?:date|time|timestamp|published|posted)(?:[-_\s]|$)`)
+	commentTextPattern   = regexp.MustCompile(`(?i)(?:^|[-_\s])(?:content|text|message)(?:[-_\s]|$)`)
+	// 评论中不属于正文的部分：回复按钮、元信息等
+	commentChromePattern = regexp.MustCompile(`(?i)(?:^|[-_\s])(?:reply|meta|actions?|says|avatar|screen-reader-text|vote|like)(?:[-_\s]|$)`)
+	// 评论条目的标签，不含 span 等行内元素，以免把代码高亮中的 <span class="comment"> 当作评论
+	commentItemTags = map[string]bool{"li": true, "div": true, "article": true, "section": true, "dd": true, "dl": true, "blockquote": true, "tr": true}
+)
+
+// Comment 一条评论
+type Comment struct {
+	Author string `json:"author,omitempty"`
+	Date   string `json:"date,omitempty"`
+	Text   string `json:"text"`
+	// 嵌套层级，顶层评论为 0，回复为 1，依此类推
+	Depth int `json:"depth"`
+}
+
+// 列出评论区中的评论，需在预处理之前调用
+func (read *Readability) getComments() []Comment {
+	body := read.dom.Find("body").First()
+	scope := body.Find("*").FilterFunction(func(i int, s *goquery.Selection) bool {
+		return matchesAny(commentRegionPattern, s.AttrOr("id", ""), s.AttrOr("class", "")) &&
+			s.ParentsFiltered("pre, code").Length() == 0
+	})
+	if scope.Length() == 0 {
+		// 没有评论区时只在 schema.org 的 Comment 所在的元素中查找，不把整个页面当作评论区
+		scope = body.Find(`[itemtype$="/Comment"]`).Parent()
+	}
+	// 只在最外层的评论区中查找
+	regions := make(map[*html.Node]bool)
+	for _, n := range scope.Nodes {
+		regions[n] = true
+	}
+	scope = scope.FilterFunction(func(i int, s *goquery.Selection) bool {
+		for p := s.Get(0).Parent; p != nil; p = p.Parent {
+			if regions[p] {
+				return false
+			}
+		}
+		return true
+	})
+	items := make(map[*html.Node]bool)
+	var order []*html.Node
+	scope.Find("*").Each(func(i int, s *goquery.Selection) {
+		n := s.Get(0)
+		if items[n] || !isCommentItem(s) || s.ParentsFiltered("pre, code").Length() > 0 {
+			return
+		}
+		items[n] = true
+		order = append(order, n)
+	})
+
+	// 外层没有文字的条目与其中第一个条目合并为一条评论
+	body2unit := make(map[*html.Node]*html.Node)
+	isUnit := make(map[*html.Node]bool)
+	for _, n := range order {
+		if _, merged := body2unit[n]; merged {
+			continue
+		}
+		isUnit[n] = true
+		for unit := n; !hasOwnText(unit, items); {
+			first := firstChildItem(unit, items)
+			if first == nil {
+				break
+			}
+			body2unit[first] = n
+			unit = first
+		}
+	}
+
+	var comments []Comment
+	for _, n := range order {
+		if !isUnit[n] {
+			continue
+		}
+		content := n
+		for !hasOwnText(content, items) {
+			first := firstChildItem(content, items)
+			if first == nil {
+				break
+			}
+			content = first
+		}
+		depth := 0
+		for p := n.Parent; p != nil; p = p.Parent {
+			if isUnit[p] {
+				depth++
+			}
+		}
+		if c, ok := extractComment(content, items); ok {
+			c.Depth = depth
+			comments = append(comments, c)
+		}
+	}
+	return comments
+}
+
+func matchesAny(pattern *regexp.Regexp, id, class string) bool {
+	if pattern.MatchString(id) {
+		return true
+	}
+	for _, cls := range strings.Fields(class) {
+		if pattern.MatchString(cls) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCommentItem(s *goquery.Selection) bool {
+	if !commentItemTags[s.Get(0).Data] {
+		return false
+	}
+	if strings.HasSuffix(s.AttrOr("itemtype", ""), "/Comment") {
+		return true
+	}
+	return commentIDPattern.MatchString(s.AttrOr("id", "")) || matchesAny(commentItemPattern, "", s.AttrOr("class", ""))
+}
+
+// n 中除嵌套条目以外是否有文字
+func hasOwnText(n *html.Node, items map[*html.Node]bool) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.TextNode:
+			if len(strings.TrimSpace(c.Data)) > 0 {
+				return true
+			}
+		case c.Type == html.ElementNode && !items[c]:
+			if hasOwnText(c, items) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// n 中第一个不在其他条目中的条目
+func firstChildItem(n *html.Node, items map[*html.Node]bool) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if items[c] {
+			return c
+		}
+		if first := firstChildItem(c, items); first != nil {
+			return first
+		}
+	}
+	return nil
+}
+
+// 从评论条目中取作者、日期与正文，嵌套的回复不计入
+func extractComment(n *html.Node, items map[*html.Node]bool) (Comment, bool) {
+	var c Comment
+	var author, date, text *html.Node
+	var walk func(p *html.Node)
+	walk = func(p *html.Node) {
+		for e := p.FirstChild; e != nil; e = e.NextSibling {
+			if e.Type != html.ElementNode || items[e] {
+				continue
+			}
+			class, itemprop := getAttr(e, "class"), getAttr(e, "itemprop")
+			empty := len(strings.TrimSpace(textOf(e))) == 0
+			switch {
+			case empty && e.Data != "time":
+			case author == nil && (itemprop == "author" || commentAuthorPattern.MatchString(class)):
+				author = e
+			case date == nil && (e.Data == "time" || itemprop == "dateCreated" || itemprop == "datePublished" || commentDatePattern.MatchString(class)):
+				date = e
+			case text == nil && (itemprop == "text" || commentTextPattern.MatchString(class)):
+				text = e
+			}
+			walk(e)
+		}
+	}
+	walk(n)
+
+	if author != nil {
+		// 作者中的名字，如 <div class="comment-author vcard"><img><b class="fn">名字</b><span class="says">说：</span>
+		if name := goquery.NewDocumentFromNode(author).Find(`[itemprop="name"], .fn`).First(); name.Length() > 0 {
+			author = name.Get(0)
+		}
+		c.Author = commentText(author, items, nil)
+	}
+	if date != nil {
+		if t := goquery.NewDocumentFromNode(date).Find("time").AddBack().Filter("time").First(); t.Length() > 0 {
+			date = t.Get(0)
+		}
+		c.Date = ts(getAttr(date, "datetime"))
+		if len(c.Date) == 0 {
+			c.Date = ts(getAttr(date, "content"))
+		}
+		if len(c.Date) == 0 {
+			c.Date = commentText(date, items, nil)
+		}
+	}
+	skip := map[*html.Node]bool{}
+	if text == nil {
+		// 没有正文元素时取作者、日期以外的文字
+		text = n
+		for _, e := range []*html.Node{author, date} {
+			if e != nil {
+				skip[e] = true
+			}
+		}
+	}
+	c.Text = commentText(text, items, skip)
+	return c, len(c.Text) > 0
+}
+
+// 评论的文字，段落之间换行，跳过嵌套的条目、skip 中的元素与回复按钮等
+func commentText(n *html.Node, items, skip map[*html.Node]bool) string {
+	var b strings.Builder
+	var walk func(p *html.Node)
+	walk = func(p *html.Node) {
+		for e := p.FirstChild; e != nil; e = e.NextSibling {
+			switch {
+			case e.Type == html.TextNode:
+				b.WriteString(e.Data)
+			case e.Type != html.ElementNode || items[e] || skip[e]:
+			case e.Data == "script" || e.Data == "style" || e.Data == "button" || e.Data == "form":
+			case commentChromePattern.MatchString(getAttr(e, "class")):
+			case e.Data == "br":
+				b.WriteByte('\n')
+			case e.Data == "p" || e.Data == "div" || e.Data == "li" || e.Data == "blockquote" || e.Data == "pre":
+				b.WriteByte('\n')
+				walk(e)
+				b.WriteByte('\n')
+			default:
+				walk(e)
+			}
+		}
+	}
+	walk(n)
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.TrimSpace(normalizeSpace(strings.ReplaceAll(line, "\u00a0", " "))); len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var commentTestArticle = `<article class="post"><h1>文章标题</h1>` +
+	strings.Repeat(`<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，评论不应混入正文。</p>`, 6) +
+	`<pre><code>x := 1 <span class="comment">// 代码中的注释</span></code></pre></article>`
+
+func TestWordPressComments(t *testing.T) {
+	comment := func(id, author, date, text, children string) string {
+		return `<li id="li-comment-` + id + `" class="comment even depth-1"><article id="div-comment-` + id + `" class="comment-body">` +
+			`<footer class="comment-meta"><div class="comment-author vcard"><img class="avatar" src="a.png"><b class="fn"><a href="/u">` + author + `</a></b> <span class="says">说：</span></div>` +
+			`<div class="comment-metadata"><a href="#comment-` + id + `"><time datetime="` + date + `">2024年1月1日</time></a> <span class="edit-link"><a href="/edit">编辑</a></span></div></footer>` +
+			`<div class="comment-content">` + text + `</div>` +
+			`<div class="reply"><a class="comment-reply-link" href="#">回复</a></div></article>` + children + `</li>`
+	}
+	page := `<html><body><div id="main">` + commentTestArticle + `</div>
+<div id="comments" class="comments-area"><h2 class="comments-title">3 条评论</h2><ol class="comment-list">` +
+		comment("1", "张三", "2024-01-01T10:00:00+08:00", `<p>写得很好。</p><p>第二段。</p>`,
+			`<ol class="children">`+comment("2", "李四", "2024-01-01T11:00:00+08:00", `<p>同意&nbsp;楼上。</p>`, "")+`</ol>`) +
+		comment("3", "王五", "2024-01-02T09:00:00+08:00", `<p>有个问题。</p>`, "") +
+		`</ol><div id="respond"><form><textarea name="comment"></textarea></form></div></div></body></html>`
+
+	article, err := New(Option{}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Comments != nil {
+		t.Errorf("默认不应提取评论：%+v", article.Comments)
+	}
+	content := article.Content
+
+	article, err = New(Option{ExtractComments: true}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Comment{
+		{Author: "张三", Date: "2024-01-01T10:00:00+08:00", Text: "写得很好。\n第二段。", Depth: 0},
+		{Author: "李四", Date: "2024-01-01T11:00:00+08:00", Text: "同意 楼上。", Depth: 1},
+		{Author: "王五", Date: "2024-01-02T09:00:00+08:00", Text: "有个问题。", Depth: 0},
+	}
+	if !reflect.DeepEqual(article.Comments, want) {
+		t.Errorf("Comments = %+v", article.Comments)
+	}
+	if article.Content != content {
+		t.Errorf("提取评论不应影响正文")
+	}
+}
+
+func TestGenericComments(t *testing.T) {
+	page := `<html><body>` + commentTestArticle + `<section class="comments">
+<div class="comment" itemscope itemtype="https://schema.org/Comment">
+  <span itemprop="author">网友甲</span><span class="comment-date">3 小时前</span>
+  <div itemprop="text">第一条评论<br>换行</div>
+  <div class="replies">
+    <div class="comment"><a class="username" href="/u/2">网友乙</a><span class="time">2 小时前</span>回复的内容<a class="reply-btn" href="#">回复</a></div>
+  </div>
+</div>
+<div class="comment"><span class="nickname">网友丙</span><p>没有时间的评论</p></div>
+<div class="comment"><span class="author">只有作者</span></div>
+</section></body></html>`
+	article, err := New(Option{ExtractComments: true}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Comment{
+		{Author: "网友甲", Date: "3 小时前", Text: "第一条评论\n换行", Depth: 0},
+		{Author: "网友乙", Date: "2 小时前", Text: "回复的内容", Depth: 1},
+		{Author: "网友丙", Text: "没有时间的评论", Depth: 0},
+	}
+	if !reflect.DeepEqual(article.Comments, want) {
+		t.Errorf("Comments = %+v", article.Comments)
+	}
+}
+
+func TestNoComments(t *testing.T) {
+	// 没有评论区时，class 为 review 的正文不是评论
+	page := `<html><body><div class="review"><h1>某款耳机评测</h1>` +
+		strings.Repeat(`<p>这是一篇产品评测的正文，介绍了音质、佩戴舒适度与续航等方面的表现，并不是评论。</p>`, 6) +
+		`</div></body></html>`
+	article, err := New(Option{ExtractComments: true}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(article.Comments) > 0 {
+		t.Errorf("不应提取到评论：%+v", article.Comments)
+	}
+
+	// 没有评论区时仍识别 schema.org 的 Comment
+	page = `<html><body>` + commentTestArticle + `<div>` +
+		`<div itemscope itemtype="https://schema.org/Comment"><span itemprop="author">网友甲</span><p itemprop="text">第一条评论</p></div>` +
+		`<div itemscope itemtype="https://schema.org/Comment"><span itemprop="author">网友乙</span><p itemprop="text">第二条评论</p></div>` +
+		`</div></body></html>`
+	article, err = New(Option{ExtractComments: true}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Comment{{Author: "网友甲", Text: "第一条评论"}, {Author: "网友乙", Text: "第二条评论"}}
+	if !reflect.DeepEqual(article.Comments, want) {
+		t.Errorf("Comments = %+v", article.Comments)
+	}
+}
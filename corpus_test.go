@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var update = flag.Bool("update", false, "重新生成 testdata/corpus 中的期望结果")
+
+// 语料中所有页面使用同一个地址，保证相对链接的转换结果稳定
+const corpusPageURL = "http://fakehost/test/page.html"
+
+type corpusMetadata struct {
+	Title   string   `json:"title"`
+	Byline  string   `json:"byline"`
+	Authors []Author `json:"authors,omitempty"`
+	Source  string   `json:"source,omitempty"`
+	Editor  string   `json:"editor,omitempty"`
+	Excerpt string   `json:"excerpt"`
+	Dir     string   `json:"dir,omitempty"`
+	Length  int      `json:"length"`
+}
+
+func TestCorpus(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "corpus", "*", "source.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) == 0 {
+		t.Fatal("testdata/corpus 为空")
+	}
+	for _, source := range dirs {
+		dir := filepath.Dir(source)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			source, err := os.ReadFile(filepath.Join(dir, "source.html"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			article, err := New(Option{PageURL: corpusPageURL}).Parse(string(source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := normalizeDOM(article.Content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			metadata, err := json.MarshalIndent(corpusMetadata{
+				Title:   article.Title,
+				Byline:  article.Byline,
+				Authors: article.Authors,
+				Source:  article.Source,
+				Editor:  article.Editor,
+				Excerpt: article.Excerpt,
+				Dir:     article.Dir,
+				Length:  article.Length,
+			}, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			metadata = append(metadata, '\n')
+
+			if *update {
+				if err := os.WriteFile(filepath.Join(dir, "expected.html"), []byte(article.Content+"\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "expected-metadata.json"), metadata, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expectedContent, err := os.ReadFile(filepath.Join(dir, "expected.html"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := normalizeDOM(string(expectedContent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if content != expected {
+				t.Errorf("正文与 expected.html 不一致：\n%s", firstDifference(expected, content))
+			}
+			expectedMetadata, err := os.ReadFile(filepath.Join(dir, "expected-metadata.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(metadata) != string(expectedMetadata) {
+				t.Errorf("元数据与 expected-metadata.json 不一致：\n%s", firstDifference(string(expectedMetadata), string(metadata)))
+			}
+		})
+	}
+}
+
+// 将 HTML 片段规范化为每行一个节点的形式：忽略空白文本节点，合并连续空白，属性按名称排序
+func normalizeDOM(s string) (string, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(s), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	var walk func(n *html.Node, depth int)
+	walk = func(n *html.Node, depth int) {
+		indent := strings.Repeat("  ", depth)
+		switch n.Type {
+		case html.TextNode:
+			if text := normalizeSpace(strings.Join(strings.Fields(n.Data), " ")); len(text) > 0 {
+				b.WriteString(indent + "#text: " + text + "\n")
+			}
+		case html.ElementNode:
+			attrs := make([]string, 0, len(n.Attr))
+			for _, a := range n.Attr {
+				attrs = append(attrs, a.Key+"="+a.Val)
+			}
+			sort.Strings(attrs)
+			b.WriteString(indent + "<" + n.Data)
+			if len(attrs) > 0 {
+				b.WriteString(" " + strings.Join(attrs, " "))
+			}
+			b.WriteString(">\n")
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c, depth+1)
+			}
+		}
+	}
+	for _, n := range nodes {
+		walk(n, 0)
+	}
+	return b.String(), nil
+}
+
+// 返回两段文本第一处不同的行，便于定位差异
+func firstDifference(expected, actual string) string {
+	e := strings.Split(expected, "\n")
+	a := strings.Split(actual, "\n")
+	for i := 0; i < len(e) || i < len(a); i++ {
+		var el, al string
+		if i < len(e) {
+			el = e[i]
+		}
+		if i < len(a) {
+			al = a[i]
+		}
+		if el != al {
+			return fmt.Sprintf("第 %d 行\n期望：%s\n实际：%s", i+1, el, al)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,221 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// EmbedProvider 允许保留的嵌入内容来源
+type EmbedProvider struct {
+	Name string
+	// 匹配 iframe、embed、object 的地址，以 //主机 开头，命名分组 id 为媒体 ID，可以不匹配。
+	// 匹配的是地址中从 //主机 开始的部分，须从开头匹配并包含完整的主机名
+	Pattern *regexp.Regexp
+	// 规范地址的模板，按 regexp.Expand 的语法引用 Pattern 的分组，如 ${id}；
+	// 为空或 id 未匹配时使用嵌入地址
+	URL string
+	// 以 <script src> 嵌入时（如 Gist）替换为 iframe，值为 iframe 地址的模板，为空时删除脚本
+	ScriptFrame string
+}
+
+// Embed 正文中保留的嵌入内容
+type Embed struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id,omitempty"`
+	// 规范地址，如视频页面的地址
+	URL string `json:"url"`
+	// 嵌入地址，即 iframe 等的 src
+	Src string `json:"src"`
+}
+
+var (
+	// DefaultEmbedProviders Option.EmbedProviders 为 nil 时使用的来源：YouTube、Vimeo、
+	// Dailymotion、腾讯视频、Twitch、Internet Archive 与 Wikimedia
+	DefaultEmbedProviders = []EmbedProvider{
+		{
+			Name:    "YouTube",
+			Pattern: regexp.MustCompile(`(?i)//(?:www\.)?youtube(?:-nocookie)?\.com(?:/(?:embed|v)/(?P<id>[\w-]{6,}))?`),
+			URL:     "https://www.youtube.com/watch?v=${id}",
+		},
+		{
+			Name:    "Vimeo",
+			Pattern: regexp.MustCompile(`(?i)//player\.vimeo\.com(?:/video/(?P<id>\d+))?`),
+			URL:     "https://vimeo.com/${id}",
+		},
+		{
+			Name:    "Dailymotion",
+			Pattern: regexp.MustCompile(`(?i)//(?:www\.)?dailymotion\.com(?:/embed/video/(?P<id>[a-z0-9]+))?`),
+			URL:     "https://www.dailymotion.com/video/${id}",
+		},
+		{
+			Name:    "Tencent Video",
+			Pattern: regexp.MustCompile(`(?i)//v\.qq\.com(?:/[^"'|]*[?&]vid=(?P<id>\w+))?`),
+			URL:     "https://v.qq.com/x/page/${id}.html",
+		},
+		{
+			Name:    "Twitch",
+			Pattern: regexp.MustCompile(`(?i)//player\.twitch\.tv(?:/\?(?:[^"'|]*&)?channel=(?P<id>\w+))?`),
+			URL:     "https://www.twitch.tv/${id}",
+		},
+		{
+			Name:    "Internet Archive",
+			Pattern: regexp.MustCompile(`(?i)//(?:www\.)?archive\.org(?:/embed/(?P<id>[^/?#&"'|]+))?`),
+			URL:     "https://archive.org/details/${id}",
+		},
+		{
+			Name:    "Wikimedia",
+			Pattern: regexp.MustCompile(`(?i)//upload\.wikimedia\.org`),
+		},
+	}
+	// KnownEmbedProviders 内置的全部来源，在 DefaultEmbedProviders 之外还有
+	// Bilibili、优酷、SoundCloud、CodePen 与 GitHub Gist
+	KnownEmbedProviders = append(DefaultEmbedProviders[:len(DefaultEmbedProviders):len(DefaultEmbedProviders)],
+		EmbedProvider{
+			Name:    "Bilibili",
+			Pattern: regexp.MustCompile(`(?i)//player\.bilibili\.com(?:/player\.html\?(?:[^"'|]*&)?bvid=(?P<id>BV\w+))?`),
+			URL:     "https://www.bilibili.com/video/${id}",
+		},
+		EmbedProvider{
+			Name:    "Youku",
+			Pattern: regexp.MustCompile(`(?i)//player\.youku\.com(?:/embed/(?P<id>[\w=]+))?`),
+			URL:     "https://v.youku.com/v_show/id_${id}.html",
+		},
+		EmbedProvider{
+			Name:    "SoundCloud",
+			Pattern: regexp.MustCompile(`(?i)//w\.soundcloud\.com/player/(?:\?(?:[^"'|]*&)?url=https?(?::|%3A)(?://|%2F%2F)api\.soundcloud\.com(?:/|%2F)tracks(?:/|%2F)(?P<id>\d+))?`),
+			URL:     "https://api.soundcloud.com/tracks/${id}",
+		},
+		EmbedProvider{
+			Name:    "CodePen",
+			Pattern: regexp.MustCompile(`(?i)//codepen\.io/(?P<user>[\w-]+)/(?:embed|pen)/(?:preview/)?(?P<id>\w+)`),
+			URL:     "https://codepen.io/${user}/pen/${id}",
+		},
+		EmbedProvider{
+			Name:        "GitHub Gist",
+			Pattern:     regexp.MustCompile(`(?i)//gist\.github\.com/(?P<user>[\w-]+)/(?P<id>[0-9a-f]{8,})`),
+			URL:         "https://gist.github.com/${user}/${id}",
+			ScriptFrame: "https://gist.github.com/${user}/${id}.pibb",
+		},
+	)
+)
+
+func (read *Readability) embedProviders() []EmbedProvider {
+	if read.option.EmbedProviders != nil {
+		return read.option.EmbedProviders
+	}
+	return DefaultEmbedProviders
+}
+
+// 以 p.Pattern 匹配地址 s，返回 s 中从 //主机 开始的部分与匹配的位置。
+// 匹配须从主机开始并包含完整的主机名，youtube.com 不会匹配 evil.com/?youtube.com 或 youtube.com.evil.com
+func matchProvider(p EmbedProvider, s string) (string, []int) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil || len(u.Host) == 0 || (len(u.Scheme) > 0 && u.Scheme != "http" && u.Scheme != "https") {
+		return "", nil
+	}
+	host := "//" + u.Host
+	target := host + u.EscapedPath()
+	if len(u.RawQuery) > 0 {
+		target += "?" + u.RawQuery
+	}
+	m := p.Pattern.FindStringSubmatchIndex(target)
+	if m == nil || m[0] != 0 || m[1] < len(host) {
+		return "", nil
+	}
+	return target, m
+}
+
+// 查找地址 s 第一个匹配的来源
+func (read *Readability) matchEmbed(s string) (Embed, bool) {
+	for _, p := range read.embedProviders() {
+		target, m := matchProvider(p, s)
+		if m == nil {
+			continue
+		}
+		e := Embed{Provider: p.Name}
+		if i := p.Pattern.SubexpIndex("id"); i > 0 && m[2*i] >= 0 {
+			e.ID = target[m[2*i]:m[2*i+1]]
+			if len(p.URL) > 0 {
+				e.URL = string(p.Pattern.ExpandString(nil, p.URL, target, m))
+			}
+		}
+		return e, true
+	}
+	return Embed{}, false
+}
+
+// 依次检查 iframe、embed、object 的属性与其中的 <param>、<embed> 等的属性，返回匹配的来源
+func (read *Readability) matchEmbedSelection(s *goquery.Selection) (Embed, bool) {
+	for _, n := range append(s.Nodes[:1:1], s.Find("*").Nodes...) {
+		for _, a := range n.Attr {
+			if e, ok := read.matchEmbed(a.Val); ok {
+				return e, true
+			}
+		}
+	}
+	return Embed{}, false
+}
+
+// 将 <script src> 形式的嵌入替换为 iframe，需在预处理删除脚本之前调用
+func (read *Readability) replaceScriptEmbeds() {
+	read.dom.Find("script[src]").Each(func(i int, script *goquery.Selection) {
+		src := script.AttrOr("src", "")
+		for _, p := range read.embedProviders() {
+			target, m := matchProvider(p, src)
+			if m == nil || len(p.ScriptFrame) == 0 {
+				continue
+			}
+			frame := string(p.Pattern.ExpandString(nil, p.ScriptFrame, target, m))
+			n := script.Get(0)
+			n.Type, n.Data, n.DataAtom, n.Namespace = html.ElementNode, "iframe", atom.Iframe, ""
+			n.Attr = []html.Attribute{{Key: "src", Val: frame}}
+			for n.FirstChild != nil {
+				n.RemoveChild(n.FirstChild)
+			}
+			return
+		}
+	})
+}
+
+// 列出正文中保留的嵌入内容，包括整理过的社交网站嵌入
+func (read *Readability) getEmbeds(articleContent *goquery.Selection) []Embed {
+	var embeds []Embed
+	articleContent.Find("iframe, embed, object, blockquote").Each(func(i int, s *goquery.Selection) {
+		if s.Get(0).Data == "blockquote" {
+			if e, ok := read.getSocialEmbed(s); ok {
+				embeds = append(embeds, e)
+			}
+			return
+		}
+		// object 中的 embed 已随 object 计入
+		if s.Get(0).Data == "embed" && s.ParentsFiltered("object").Length() > 0 {
+			return
+		}
+		e, ok := read.matchEmbedSelection(s)
+		if !ok {
+			return
+		}
+		src := s.AttrOr("src", s.AttrOr("data", ""))
+		if len(src) == 0 {
+			src = s.Find("embed[src]").AttrOr("src", "")
+		}
+		if len(src) > 0 {
+			e.Src = read.resolveURL(src)
+		}
+		if len(e.URL) == 0 {
+			e.URL = e.Src
+		}
+		embeds = append(embeds, e)
+	})
+	return embeds
+}
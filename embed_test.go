@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func testEmbedPage() string {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的视频。</p>`
+	return `<html><body><article>` + strings.Repeat(paragraph, 3) +
+		`<p><iframe src="https://www.youtube.com/embed/dQw4w9WgXcQ?rel=0" width="560"></iframe></p>` +
+		`<p><iframe src="//player.bilibili.com/player.html?aid=1&bvid=BV1xx411c7mD&page=1"></iframe></p>` +
+		`<p><iframe src="https://ads.example.com/banner"></iframe></p>` +
+		`<script src="https://gist.github.com/octocat/6cad326836d38bd3a7ae.js"></script>` +
+		strings.Repeat(paragraph, 3) + `</article></body></html>`
+}
+
+func TestEmbedsDefault(t *testing.T) {
+	article, err := New(Option{PageURL: "https://example.com/post"}).Parse(testEmbedPage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Embed{{
+		Provider: "YouTube",
+		ID:       "dQw4w9WgXcQ",
+		URL:      "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		Src:      "https://www.youtube.com/embed/dQw4w9WgXcQ?rel=0",
+	}}
+	if !reflect.DeepEqual(article.Embeds, want) {
+		t.Errorf("Embeds = %+v", article.Embeds)
+	}
+	for _, s := range []string{"bilibili", "ads.example.com", "gist.github.com"} {
+		if strings.Contains(article.Content, s) {
+			t.Errorf("默认白名单不应保留 %s：%s", s, article.Content)
+		}
+	}
+
+	// 空切片表示不保留任何嵌入内容
+	article, err = New(Option{PageURL: "https://example.com/post", EmbedProviders: []EmbedProvider{}}).Parse(testEmbedPage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(article.Embeds) > 0 || strings.Contains(article.Content, "<iframe") {
+		t.Errorf("不应保留嵌入内容：%+v\n%s", article.Embeds, article.Content)
+	}
+}
+
+func TestEmbedsKnownProviders(t *testing.T) {
+	article, err := New(Option{PageURL: "https://example.com/post", EmbedProviders: KnownEmbedProviders}).Parse(testEmbedPage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Embed{
+		{"YouTube", "dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "https://www.youtube.com/embed/dQw4w9WgXcQ?rel=0"},
+		{"Bilibili", "BV1xx411c7mD", "https://www.bilibili.com/video/BV1xx411c7mD", "https://player.bilibili.com/player.html?aid=1&bvid=BV1xx411c7mD&page=1"},
+		{"GitHub Gist", "6cad326836d38bd3a7ae", "https://gist.github.com/octocat/6cad326836d38bd3a7ae", "https://gist.github.com/octocat/6cad326836d38bd3a7ae.pibb"},
+	}
+	if !reflect.DeepEqual(article.Embeds, want) {
+		t.Errorf("Embeds = %+v", article.Embeds)
+	}
+	if strings.Contains(article.Content, "ads.example.com") {
+		t.Errorf("不在白名单中的 iframe 应被删除：%s", article.Content)
+	}
+}
+
+func TestEmbedsCustomProvider(t *testing.T) {
+	providers := []EmbedProvider{{
+		Name:    "Example Player",
+		Pattern: regexp.MustCompile(`//ads\.example\.com/(?P<id>\w+)`),
+	}}
+	article, err := New(Option{EmbedProviders: providers}).Parse(testEmbedPage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Embed{{"Example Player", "banner", "https://ads.example.com/banner", "https://ads.example.com/banner"}}
+	if !reflect.DeepEqual(article.Embeds, want) || strings.Contains(article.Content, "youtube") {
+		t.Errorf("Embeds = %+v", article.Embeds)
+	}
+}
+
+func TestMatchEmbed(t *testing.T) {
+	cases := []struct {
+		src string
+		e   Embed
+	}{
+		{"https://player.vimeo.com/video/76979871", Embed{Provider: "Vimeo", ID: "76979871", URL: "https://vimeo.com/76979871"}},
+		{"https://v.qq.com/txp/iframe/player.html?vid=w0027cm3ewl", Embed{Provider: "Tencent Video", ID: "w0027cm3ewl", URL: "https://v.qq.com/x/page/w0027cm3ewl.html"}},
+		{"https://player.youku.com/embed/XNDk2NjQ0NjQ4MA==", Embed{Provider: "Youku", ID: "XNDk2NjQ0NjQ4MA==", URL: "https://v.youku.com/v_show/id_XNDk2NjQ0NjQ4MA==.html"}},
+		{"https://w.soundcloud.com/player/?url=https%3A//api.soundcloud.com/tracks/293&auto_play=false", Embed{Provider: "SoundCloud", ID: "293", URL: "https://api.soundcloud.com/tracks/293"}},
+		{"https://codepen.io/team/embed/preview/PNaGbb?height=300", Embed{Provider: "CodePen", ID: "PNaGbb", URL: "https://codepen.io/team/pen/PNaGbb"}},
+		{"https://www.youtube.com/user/someone", Embed{Provider: "YouTube"}},
+	}
+	read := New(Option{EmbedProviders: KnownEmbedProviders})
+	for _, c := range cases {
+		if e, ok := read.matchEmbed(c.src); !ok || e != c.e {
+			t.Errorf("%s: %+v，期望 %+v", c.src, e, c.e)
+		}
+	}
+	for _, src := range []string{
+		"https://example.com/embed/1",
+		// 来源须是地址的主机
+		"https://evil.com/?youtube.com",
+		"https://evil.com/www.youtube.com/embed/dQw4w9WgXcQ",
+		"https://www.youtube.com.evil.com/embed/dQw4w9WgXcQ",
+		"https://evil.com/#//player.vimeo.com",
+		"javascript://www.youtube.com/%0aalert(1)",
+	} {
+		if e, ok := read.matchEmbed(src); ok {
+			t.Errorf("%s: 不应匹配，得到 %+v", src, e)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/transform"
+)
+
+// 全文输出：为 RSS 2.0 / RSS 1.0 / Atom 中的每个条目获取原文并提取正文，
+// 写入 RSS 的 content:encoded 或 Atom 的 <content>。其余内容按原样保留，
+// 因此 enclosure 等扩展元素不会丢失。
+
+const (
+	rssContentNamespace  = "http://purl.org/rss/1.0/modules/content/"
+	atomNamespace        = "http://www.w3.org/2005/Atom"
+	defaultFeedWorkers   = 4
+	defaultFeedCacheSize = 1000
+)
+
+var xmlEncodingPattern = regexp.MustCompile(`^(<\?xml[^>]*encoding\s*=\s*["'])([^"']+)(["'])`)
+
+// ErrNotFeed 不是 RSS 或 Atom
+var ErrNotFeed = errors.New("不是 RSS 或 Atom")
+
+// FeedCache 缓存已提取的条目，键为条目的链接
+type FeedCache interface {
+	Get(link string) (*Article, bool)
+	Set(link string, article *Article)
+}
+
+// FeedOption 全文输出配置
+type FeedOption struct {
+	// 提取正文的配置，Option.Fetcher 同时用于获取条目原文，为空时使用 HTTPFetcher
+	Option Option
+	// 为空时不缓存
+	Cache FeedCache
+	// 同时获取的条目数
+	Workers int
+	// 最多处理的条目数，0 表示不限制，其余条目原样输出
+	MaxItems int
+}
+
+// FeedProxy 将摘要输出转换为全文输出
+type FeedProxy struct {
+	option  FeedOption
+	fetcher Fetcher
+}
+
+// 条目在原文中的位置
+type feedItem struct {
+	link string
+	// 条目结束标签的起始位置，新内容插入在此处
+	end int64
+	// 需要删除的原有正文元素 [start, end)
+	content [][2]int64
+}
+
+// NewFeedProxy 新建全文输出
+func NewFeedProxy(o FeedOption) *FeedProxy {
+	if o.Workers <= 0 {
+		o.Workers = defaultFeedWorkers
+	}
+	fetcher := o.Option.Fetcher
+	if fetcher == nil {
+		fetcher = &HTTPFetcher{
+			Client:      o.Option.HTTPClient,
+			UserAgent:   o.Option.UserAgent,
+			MaxBodySize: o.Option.MaxBodySize,
+			Timeout:     o.Option.Timeout,
+		}
+	}
+	return &FeedProxy{option: o, fetcher: fetcher}
+}
+
+// ConvertURL 获取并转换 feedURL
+func (p *FeedProxy) ConvertURL(ctx context.Context, feedURL string) ([]byte, error) {
+	o := p.option.Option
+	f := &HTTPFetcher{Client: o.HTTPClient, UserAgent: o.UserAgent, MaxBodySize: o.MaxBodySize, Timeout: o.Timeout}
+	feed, finalURL, _, err := f.get(ctx, feedURL)
+	if err != nil {
+		return nil, err
+	}
+	return p.Convert(ctx, feed, finalURL)
+}
+
+// Convert 转换 feed，feedURL 用于转换相对的条目链接，可以为空。
+// 获取或提取失败的条目保持原样。
+func (p *FeedProxy) Convert(ctx context.Context, feed []byte, feedURL string) ([]byte, error) {
+	feed, err := feedToUTF8(feed)
+	if err != nil {
+		return nil, err
+	}
+	root, items, err := scanFeed(feed)
+	if err != nil {
+		return nil, err
+	}
+	if p.option.MaxItems > 0 && len(items) > p.option.MaxItems {
+		items = items[:p.option.MaxItems]
+	}
+	for i := range items {
+		items[i].link = resolveFeedLink(feedURL, items[i].link)
+	}
+	articles := p.extractItems(ctx, items)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.Grow(len(feed))
+	var last int64
+	// RSS 需要声明 content 命名空间
+	if root.Name.Local != "feed" && !hasNamespacePrefix(root, "content") {
+		b.Write(feed[:root.end-1])
+		b.WriteString(` xmlns:content="` + rssContentNamespace + `"`)
+		last = root.end - 1
+	}
+	for i, item := range items {
+		if articles[i] == nil {
+			continue
+		}
+		for _, c := range item.content {
+			b.Write(feed[last:c[0]])
+			last = c[1]
+		}
+		b.Write(feed[last:item.end])
+		last = item.end
+		if root.Name.Local == "feed" {
+			b.WriteString(`<content type="html">`)
+			xml.EscapeText(&b, []byte(articles[i].Content))
+			b.WriteString(`</content>`)
+		} else {
+			b.WriteString(`<content:encoded><![CDATA[`)
+			b.WriteString(strings.ReplaceAll(articles[i].Content, "]]>", "]]]]><![CDATA[>"))
+			b.WriteString(`]]></content:encoded>`)
+		}
+	}
+	b.Write(feed[last:])
+	return b.Bytes(), nil
+}
+
+// 以 Workers 个协程获取并提取条目，失败的条目结果为 nil
+func (p *FeedProxy) extractItems(ctx context.Context, items []feedItem) []*Article {
+	articles := make([]*Article, len(items))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < p.option.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				articles[i] = p.extractItem(ctx, items[i].link)
+			}
+		}()
+	}
+	for i := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return articles
+}
+
+func (p *FeedProxy) extractItem(ctx context.Context, link string) *Article {
+	if len(link) == 0 {
+		return nil
+	}
+	if p.option.Cache != nil {
+		if article, has := p.option.Cache.Get(link); has {
+			return article
+		}
+	}
+	h, err := p.fetcher.Fetch(ctx, link)
+	if err != nil {
+		return nil
+	}
+	o := p.option.Option
+	o.PageURL = link
+	article, err := New(o).ParseContext(ctx, h)
+	if err != nil {
+		return nil
+	}
+	if p.option.Cache != nil {
+		p.option.Cache.Set(link, article)
+	}
+	return article
+}
+
+// 根元素及其起始标签结束的位置
+type feedRoot struct {
+	xml.StartElement
+	end int64
+}
+
+// 找出所有条目的链接、正文元素以及结束标签的位置
+func scanFeed(feed []byte) (feedRoot, []feedItem, error) {
+	var root feedRoot
+	var items []feedItem
+	d := xml.NewDecoder(bytes.NewReader(feed))
+	// 容忍 HTML 实体等常见的不规范写法；不启用 AutoClose，否则 RSS 的 <link> 会被当作空元素
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+
+	var item *feedItem
+	// 条目内的元素路径，第一个为条目本身
+	var path []xml.Name
+	var contentStart int64
+	var text strings.Builder
+	depth := 0
+	for {
+		offset := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return root, nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				root = feedRoot{t.Copy(), d.InputOffset()}
+				if t.Name.Local != "rss" && t.Name.Local != "RDF" && !(t.Name.Local == "feed" && t.Name.Space == atomNamespace) {
+					return root, nil, ErrNotFeed
+				}
+				continue
+			}
+			if item == nil {
+				if isFeedItem(root, t.Name) {
+					item = &feedItem{}
+					path = []xml.Name{t.Name}
+				}
+				continue
+			}
+			path = append(path, t.Name)
+			text.Reset()
+			if len(path) == 2 {
+				switch {
+				case isFeedContent(root, t.Name):
+					contentStart = offset
+				case root.Name.Local == "feed" && t.Name.Local == "link":
+					if rel := attrValue(t.Attr, "rel"); (rel == "" || rel == "alternate") && len(item.link) == 0 {
+						item.link = attrValue(t.Attr, "href")
+					}
+				}
+			}
+		case xml.CharData:
+			if item != nil {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			depth--
+			if item == nil {
+				continue
+			}
+			if len(path) == 1 {
+				item.end = offset
+				items = append(items, *item)
+				item = nil
+				continue
+			}
+			if len(path) == 2 {
+				name := path[1]
+				switch {
+				case isFeedContent(root, name):
+					item.content = append(item.content, [2]int64{contentStart, d.InputOffset()})
+				case root.Name.Local != "feed" && name.Local == "link" && name.Space != atomNamespace:
+					item.link = strings.TrimSpace(text.String())
+				case name.Local == "guid" && len(item.link) == 0 && looksLikeURL(text.String()):
+					// 没有 link 时使用作为永久链接的 guid
+					item.link = strings.TrimSpace(text.String())
+				}
+			}
+			path = path[:len(path)-1]
+		}
+	}
+	if root.end == 0 {
+		return root, nil, ErrNotFeed
+	}
+	return root, items, nil
+}
+
+func isFeedItem(root feedRoot, name xml.Name) bool {
+	if root.Name.Local == "feed" {
+		return name.Local == "entry" && name.Space == atomNamespace
+	}
+	return name.Local == "item"
+}
+
+func isFeedContent(root feedRoot, name xml.Name) bool {
+	if root.Name.Local == "feed" {
+		return name.Local == "content" && name.Space == atomNamespace
+	}
+	return name.Local == "encoded" && name.Space == rssContentNamespace
+}
+
+func hasNamespacePrefix(root feedRoot, prefix string) bool {
+	for _, a := range root.Attr {
+		if a.Name.Space == "xmlns" && a.Name.Local == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+func attrValue(attrs []xml.Attr, name string) string {
+	for _, a := range attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func looksLikeURL(s string) bool {
+	s = strings.TrimSpace(s)
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+func resolveFeedLink(feedURL, link string) string {
+	if len(feedURL) == 0 || len(link) == 0 {
+		return link
+	}
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// 非 UTF-8 编码的 feed 转换为 UTF-8，并修改 XML 声明中的编码
+func feedToUTF8(feed []byte) ([]byte, error) {
+	m := xmlEncodingPattern.FindSubmatchIndex(feed)
+	if m == nil {
+		return feed, nil
+	}
+	enc, name := charset.Lookup(string(feed[m[4]:m[5]]))
+	if enc == nil || name == "utf-8" {
+		return feed, nil
+	}
+	decoded, _, err := transform.Bytes(enc.NewDecoder(), feed)
+	if err != nil {
+		return nil, err
+	}
+	// 声明只包含 ASCII，转换前后位置不变
+	return append(append(append([]byte{}, decoded[:m[4]]...), "utf-8"...), decoded[m[5]:]...), nil
+}
+
+// MemoryFeedCache 最近最少使用的内存缓存
+type MemoryFeedCache struct {
+	size  int
+	mu    sync.Mutex
+	list  *list.List
+	items map[string]*list.Element
+}
+
+type memoryFeedCacheEntry struct {
+	link    string
+	article *Article
+}
+
+// NewMemoryFeedCache 新建最多保存 size 个条目的缓存，size 不大于 0 时使用默认值
+func NewMemoryFeedCache(size int) *MemoryFeedCache {
+	if size <= 0 {
+		size = defaultFeedCacheSize
+	}
+	return &MemoryFeedCache{size: size, list: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Get 获取缓存的条目
+func (c *MemoryFeedCache) Get(link string) (*Article, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, has := c.items[link]
+	if !has {
+		return nil, false
+	}
+	c.list.MoveToFront(e)
+	return e.Value.(*memoryFeedCacheEntry).article, true
+}
+
+// Set 缓存条目，超出容量时淘汰最久未使用的条目
+func (c *MemoryFeedCache) Set(link string, article *Article) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, has := c.items[link]; has {
+		e.Value.(*memoryFeedCacheEntry).article = article
+		c.list.MoveToFront(e)
+		return
+	}
+	c.items[link] = c.list.PushFront(&memoryFeedCacheEntry{link, article})
+	for c.list.Len() > c.size {
+		e := c.list.Back()
+		c.list.Remove(e)
+		delete(c.items, e.Value.(*memoryFeedCacheEntry).link)
+	}
+}
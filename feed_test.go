@@ -0,0 +1,216 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// 按地址返回固定页面的 Fetcher，并记录获取次数
+type testFeedFetcher struct {
+	mu     sync.Mutex
+	pages  map[string]string
+	counts map[string]int
+}
+
+func (f *testFeedFetcher) Fetch(ctx context.Context, pageURL string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.counts[pageURL]++
+	if h, has := f.pages[pageURL]; has {
+		return h, nil
+	}
+	return "", errors.New("not found")
+}
+
+func newTestFeedFetcher() *testFeedFetcher {
+	return &testFeedFetcher{
+		pages: map[string]string{
+			"http://example.com/posts/1.html": testFetchPage(),
+			"http://example.com/posts/2.html": strings.Replace(testFetchPage(), "这是一段足够长的正文文字", "第二篇]]>文章的正文", 1),
+		},
+		counts: make(map[string]int),
+	}
+}
+
+const testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+<title>示例博客</title>
+<link>http://example.com/</link>
+<atom:link href="http://example.com/feed.xml" rel="self"/>
+<item>
+<title>第一篇</title>
+<link>/posts/1.html</link>
+<description>摘要 &amp; 截断…</description>
+<enclosure url="http://example.com/1.mp3" length="1" type="audio/mpeg"/>
+</item>
+<item>
+<title>第二篇</title>
+<guid isPermaLink="true">http://example.com/posts/2.html</guid>
+</item>
+<item>
+<title>失效链接</title>
+<link>http://example.com/posts/404.html</link>
+</item>
+</channel>
+</rss>`
+
+type testRSSFeed struct {
+	Items []struct {
+		Title     string `xml:"title"`
+		Content   string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+		Enclosure struct {
+			URL string `xml:"url,attr"`
+		} `xml:"enclosure"`
+	} `xml:"channel>item"`
+}
+
+func TestFeedProxyRSS(t *testing.T) {
+	fetcher := newTestFeedFetcher()
+	proxy := NewFeedProxy(FeedOption{Option: Option{Fetcher: fetcher}, Cache: NewMemoryFeedCache(0)})
+	out, err := proxy.Convert(context.Background(), []byte(testRSS), "http://example.com/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed testRSSFeed
+	if err := xml.Unmarshal(out, &feed); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if len(feed.Items) != 3 {
+		t.Fatalf("条目数 %d：\n%s", len(feed.Items), out)
+	}
+	if !strings.Contains(feed.Items[0].Content, "这是一段足够长的正文文字") || !strings.Contains(feed.Items[0].Content, "http://example.com/posts/next.html") {
+		t.Errorf("第一篇正文有误：%s", feed.Items[0].Content)
+	}
+	if feed.Items[0].Enclosure.URL != "http://example.com/1.mp3" {
+		t.Errorf("enclosure 丢失：\n%s", out)
+	}
+	if !strings.Contains(feed.Items[1].Content, "第二篇]]&gt;文章的正文") {
+		t.Errorf("第二篇正文有误：%s", feed.Items[1].Content)
+	}
+	if len(feed.Items[2].Content) != 0 {
+		t.Errorf("获取失败的条目应保持原样：%s", feed.Items[2].Content)
+	}
+	if !strings.Contains(string(out), `<description>摘要 &amp; 截断…</description>`) {
+		t.Errorf("原有内容应保持原样：\n%s", out)
+	}
+
+	// 再次转换已输出的全文，原有的 content:encoded 被替换，已处理的条目从缓存读取
+	again, err := proxy.Convert(context.Background(), out, "http://example.com/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(again), "<content:encoded>"); n != 2 {
+		t.Errorf("content:encoded 数量 %d，期望 2", n)
+	}
+	if n := strings.Count(string(again), "xmlns:content="); n != 1 {
+		t.Errorf("命名空间声明数量 %d，期望 1", n)
+	}
+	if fetcher.counts["http://example.com/posts/1.html"] != 1 || fetcher.counts["http://example.com/posts/404.html"] != 2 {
+		t.Errorf("获取次数 %v", fetcher.counts)
+	}
+}
+
+func TestFeedProxyAtom(t *testing.T) {
+	atom := `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+<title>示例</title>
+<entry>
+<title>第一篇</title>
+<link rel="alternate" type="text/html" href="http://example.com/posts/1.html"/>
+<link rel="replies" href="http://example.com/posts/1/comments"/>
+<summary>摘要</summary>
+<content type="html">&lt;p&gt;截断…&lt;/p&gt;</content>
+</entry>
+</feed>`
+	proxy := NewFeedProxy(FeedOption{Option: Option{Fetcher: newTestFeedFetcher()}})
+	out, err := proxy.Convert(context.Background(), []byte(atom), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed struct {
+		Entries []struct {
+			Summary string `xml:"summary"`
+			Content []struct {
+				Type string `xml:"type,attr"`
+				Body string `xml:",chardata"`
+			} `xml:"content"`
+		} `xml:"http://www.w3.org/2005/Atom entry"`
+	}
+	if err := xml.Unmarshal(out, &feed); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if len(feed.Entries) != 1 || len(feed.Entries[0].Content) != 1 {
+		t.Fatalf("应只有一个 content：\n%s", out)
+	}
+	c := feed.Entries[0].Content[0]
+	if c.Type != "html" || !strings.Contains(c.Body, `<div id="readability-page-1"`) || feed.Entries[0].Summary != "摘要" {
+		t.Errorf("Atom 输出有误：\n%s", out)
+	}
+}
+
+func TestFeedProxyEncoding(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String(strings.Replace(testRSS, `encoding="UTF-8"`, `encoding="GBK"`, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := NewFeedProxy(FeedOption{Option: Option{Fetcher: newTestFeedFetcher()}})
+	out, err := proxy.Convert(context.Background(), []byte(gbk), "http://example.com/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), `<?xml version="1.0" encoding="utf-8"?>`) || !strings.Contains(string(out), "<title>示例博客</title>") {
+		t.Errorf("未转换为 UTF-8：\n%s", out)
+	}
+}
+
+func TestFeedProxyMaxItems(t *testing.T) {
+	fetcher := newTestFeedFetcher()
+	proxy := NewFeedProxy(FeedOption{Option: Option{Fetcher: fetcher}, MaxItems: 1})
+	out, err := proxy.Convert(context.Background(), []byte(testRSS), "http://example.com/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(out), "<content:encoded>"); n != 1 || len(fetcher.counts) != 1 {
+		t.Errorf("content:encoded 数量 %d，获取 %v", n, fetcher.counts)
+	}
+	if !strings.Contains(string(out), "<title>失效链接</title>") {
+		t.Error("超出 MaxItems 的条目应保留")
+	}
+}
+
+func TestFeedProxyNotFeed(t *testing.T) {
+	proxy := NewFeedProxy(FeedOption{Option: Option{Fetcher: newTestFeedFetcher()}})
+	for _, s := range []string{"<html><body></body></html>", "", "<feed><entry/></feed>"} {
+		if _, err := proxy.Convert(context.Background(), []byte(s), ""); !errors.Is(err, ErrNotFeed) {
+			t.Errorf("%q: err = %v，期望 ErrNotFeed", s, err)
+		}
+	}
+}
+
+func TestMemoryFeedCache(t *testing.T) {
+	c := NewMemoryFeedCache(2)
+	c.Set("a", &Article{Title: "a"})
+	c.Set("b", &Article{Title: "b"})
+	c.Get("a")
+	c.Set("c", &Article{Title: "c"})
+	if _, has := c.Get("b"); has {
+		t.Error("最久未使用的 b 应被淘汰")
+	}
+	for _, link := range []string{"a", "c"} {
+		if a, has := c.Get(link); !has || a.Title != link {
+			t.Errorf("%s 应在缓存中", link)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultUserAgent   = "Mozilla/5.0 (compatible; go-readability/1.0; +https://github.com/naiba/go-readability)"
+	defaultMaxBodySize = 10 << 20
+	defaultTimeout     = 30 * time.Second
+)
+
+// ErrBodyTooLarge 网页大小超出 MaxBodySize
+var ErrBodyTooLarge = errors.New("网页大小超出限制")
+
+// HTTPFetcher 使用 http.Client 获取网页，可作为 Option.Fetcher 获取后续分页
+type HTTPFetcher struct {
+	Client      *http.Client
+	UserAgent   string
+	MaxBodySize int64
+	Timeout     time.Duration
+}
+
+// ParseURL 获取并解析网页，重定向后的最终地址作为 PageURL，响应头中的编码用于转码
+func ParseURL(ctx context.Context, pageURL string, o Option) (*Article, error) {
+	f := &HTTPFetcher{
+		Client:      o.HTTPClient,
+		UserAgent:   o.UserAgent,
+		MaxBodySize: o.MaxBodySize,
+		Timeout:     o.Timeout,
+	}
+	body, finalURL, cs, err := f.get(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	o.PageURL = finalURL
+	read := New(o)
+	read.ctx = ctx
+	read.charset = cs
+	return read.Parse(string(body))
+}
+
+// Fetch 获取网页并转换为 UTF-8
+func (f *HTTPFetcher) Fetch(ctx context.Context, pageURL string) (string, error) {
+	body, _, cs, err := f.get(ctx, pageURL)
+	if err != nil {
+		return "", err
+	}
+	return convertCharset(string(body), cs)
+}
+
+// 获取网页，返回内容、重定向后的地址以及响应头中声明的编码
+func (f *HTTPFetcher) get(ctx context.Context, pageURL string) ([]byte, string, string, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	userAgent := f.UserAgent
+	if len(userAgent) == 0 {
+		userAgent = defaultUserAgent
+	}
+	maxBodySize := f.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+	timeout := f.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, "", "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", "", fmt.Errorf("获取 %s 失败：%s", pageURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if err != nil {
+		return nil, "", "", err
+	}
+	if int64(len(body)) > maxBodySize {
+		return nil, "", "", ErrBodyTooLarge
+	}
+
+	var cs string
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		cs = params["charset"]
+	}
+	return body, resp.Request.URL.String(), cs, nil
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func testFetchPage() string {
+	paragraph := "<p>" + strings.Repeat("这是一段足够长的正文文字，用来让候选节点获得分数，", 4) + "完。</p>"
+	return `<html><head><title>获取网页的测试标题</title></head><body>
+<article><section class="content">` + strings.Repeat(paragraph, 5) + `<p><a href="next.html">相对链接</a></p></section></article>
+</body></html>`
+}
+
+func TestParseURL(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String(testFetchPage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var userAgent string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/news/article.html", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/news/article.html", func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		w.Header().Set("Content-Type", "text/html; charset=GBK")
+		w.Write([]byte(gbk))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	article, err := ParseURL(context.Background(), server.URL+"/old", Option{
+		HTTPClient: server.Client(),
+		UserAgent:  "test-agent",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.URL != server.URL+"/news/article.html" {
+		t.Errorf("URL = %q，期望重定向后的地址", article.URL)
+	}
+	if userAgent != "test-agent" {
+		t.Errorf("User-Agent = %q", userAgent)
+	}
+	if !strings.Contains(article.TextContent, "这是一段足够长的正文文字") {
+		t.Errorf("GBK 编码未正确转换：%s", article.TextContent)
+	}
+	if !strings.Contains(article.Content, server.URL+"/news/next.html") {
+		t.Errorf("相对链接未以最终地址为基准：%s", article.Content)
+	}
+}
+
+func TestParseURLLimits(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testFetchPage()))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, err := ParseURL(context.Background(), server.URL+"/large", Option{HTTPClient: server.Client(), MaxBodySize: 100})
+	if err != ErrBodyTooLarge {
+		t.Errorf("超出 MaxBodySize：err = %v", err)
+	}
+	_, err = ParseURL(context.Background(), server.URL+"/slow", Option{HTTPClient: server.Client(), Timeout: 50 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Errorf("超时：err = %v", err)
+	}
+	_, err = ParseURL(context.Background(), server.URL+"/missing", Option{HTTPClient: server.Client()})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("404：err = %v", err)
+	}
+}
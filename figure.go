@@ -0,0 +1,184 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 图片说明：评分之前把 "图片 + 说明" 整理为 <figure><img><figcaption>，
+// 以免说明被当作短段落删除，或与图片分离。
+
+const maxCaptionLength = 200
+
+var (
+	// 图片说明常用的 class/id，如 WordPress 的 wp-caption-text、门户网站的 img-desc、pic_txt
+	captionPattern = regexp.MustCompile(`(?i)caption|img[-_]?(desc|text|txt|info|title)|pic[-_]?(desc|text|txt|info|intro|title)|photo[-_]?(desc|text|txt|info)|image[-_]?(desc|text|txt|info)|imgtxt|pictext|picintro`)
+	// 只含图片的段落中允许出现的元素
+	imageWrapperTags = map[string]bool{"a": true, "p": true, "div": true, "span": true, "center": true, "picture": true, "source": true}
+)
+
+// Image 正文中的图片
+type Image struct {
+	Src     string `json:"src"`
+	Alt     string `json:"alt,omitempty"`
+	Caption string `json:"caption,omitempty"`
+}
+
+// 整理图片与说明，需在评分之前调用
+func (read *Readability) normalizeFigures() {
+	// 已有的 figure 中 class 像说明的子元素改为 figcaption
+	read.dom.Find("figure").Each(func(i int, figure *goquery.Selection) {
+		if figure.Find("figcaption").Length() > 0 {
+			return
+		}
+		figure.Find("*").EachWithBreak(func(i int, c *goquery.Selection) bool {
+			if isCaption(c) {
+				c.Get(0).Data, c.Get(0).DataAtom = "figcaption", atom.Figcaption
+				return false
+			}
+			return true
+		})
+	})
+
+	read.dom.Find("body *").Each(func(i int, caption *goquery.Selection) {
+		if !isCaption(caption) || caption.ParentsFiltered("figure").Length() > 0 {
+			return
+		}
+		n := caption.Get(0)
+		if n.Parent == nil {
+			return
+		}
+		// 图片与说明在同一个容器中，如 <div class="wp-caption"><img><p class="wp-caption-text">
+		// 或 <p><img><br><span class="img-desc">
+		if parent := caption.Parent(); !isBodyOrDetached(parent) && onlyImageBesides(parent.Get(0), n) {
+			if img := parent.Find("img"); img.Length() == 1 {
+				read.wrapFigure(parent.Get(0), imageOf(img.Get(0), parent.Get(0)), n)
+				return
+			}
+		}
+		// 说明紧跟在只含一张图片的元素之后，如 <p><img></p><p class="caption">
+		prev := prevElement(n)
+		if prev == nil || !onlyImageBesides(prev, nil) {
+			return
+		}
+		imgs := goquery.NewDocumentFromNode(prev).Find("img")
+		if prev.Data == "img" {
+			read.wrapFigure(prev, prev, n)
+		} else if imgs.Length() == 1 {
+			read.wrapFigure(prev, imageOf(imgs.Get(0), prev), n)
+		}
+	})
+}
+
+// class/id 像图片说明、文字不长且不含图片的元素
+func isCaption(s *goquery.Selection) bool {
+	n := s.Get(0)
+	if n.Data == "img" || n.Data == "figure" || n.Data == "body" {
+		return false
+	}
+	if !captionPattern.MatchString(s.AttrOr("class", "") + " " + s.AttrOr("id", "")) {
+		return false
+	}
+	length := textLength(s.Text())
+	return length > 0 && length <= maxCaptionLength && s.Find("img").Length() == 0
+}
+
+// n 中除 except 以外只有图片，没有文字
+func onlyImageBesides(n, except *html.Node) bool {
+	imgs := 0
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c == except:
+			case c.Type == html.TextNode:
+				if len(strings.TrimSpace(c.Data)) > 0 {
+					return false
+				}
+			case c.Type != html.ElementNode:
+			case c.Data == "img":
+				imgs++
+			case c.Data == "br":
+			case !imageWrapperTags[c.Data]:
+				return false
+			default:
+				if !walk(c) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if n.Type == html.ElementNode && n.Data == "img" {
+		return except == nil
+	}
+	return walk(n) && imgs == 1
+}
+
+// 图片及包裹它的链接，不超出 container
+func imageOf(img, container *html.Node) *html.Node {
+	if p := img.Parent; p != nil && p != container && p.Data == "a" {
+		return p
+	}
+	return img
+}
+
+func prevElement(n *html.Node) *html.Node {
+	for p := n.PrevSibling; p != nil; p = p.PrevSibling {
+		if p.Type == html.ElementNode {
+			return p
+		}
+		if p.Type == html.TextNode && len(strings.TrimSpace(p.Data)) > 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+// 在 at 的位置插入 <figure>image<figcaption>caption 的内容</figcaption></figure>，
+// 并删除 at 与 caption 的剩余部分
+func (read *Readability) wrapFigure(at, image, caption *html.Node) {
+	figure := &html.Node{Type: html.ElementNode, Data: "figure", DataAtom: atom.Figure}
+	figcaption := &html.Node{Type: html.ElementNode, Data: "figcaption", DataAtom: atom.Figcaption}
+	at.Parent.InsertBefore(figure, at)
+	image.Parent.RemoveChild(image)
+	figure.AppendChild(image)
+	for caption.FirstChild != nil {
+		c := caption.FirstChild
+		caption.RemoveChild(c)
+		figcaption.AppendChild(c)
+	}
+	figure.AppendChild(figcaption)
+	if caption.Parent != nil {
+		caption.Parent.RemoveChild(caption)
+	}
+	if at != image && at.Parent != nil {
+		at.Parent.RemoveChild(at)
+	}
+}
+
+// 列出正文中的图片，figure 中的图片附带 figcaption 的文字
+func (read *Readability) getImages(articleContent *goquery.Selection) []Image {
+	var images []Image
+	articleContent.Find("img").Each(func(i int, img *goquery.Selection) {
+		src := ts(img.AttrOr("src", ""))
+		if len(src) == 0 {
+			return
+		}
+		image := Image{Src: src, Alt: normalizeSpace(ts(img.AttrOr("alt", "")))}
+		if figure := img.Closest("figure"); figure.Length() > 0 && figure.Find("img").Length() == 1 {
+			image.Caption = normalizeSpace(ts(figure.Find("figcaption").First().Text()))
+		}
+		images = append(images, image)
+	})
+	return images
+}
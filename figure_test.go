@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFigures(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的图片。</p>`
+	page := `<html><body><article>` + strings.Repeat(paragraph, 3) +
+		`<p><img src="a.jpg" alt="图一"></p><p class="caption">说明一</p>` +
+		`<div class="wp-caption"><a href="big.jpg"><img src="b.jpg"></a><p class="wp-caption-text">说明二</p></div>` +
+		`<p style="text-align:center"><img src="c.jpg"><br><span class="img-desc">说明三</span></p>` +
+		`<figure><img src="d.jpg"><div class="pic_txt">说明四</div></figure>` +
+		`<div class="gallery"><div><img src="e.jpg"></div><div class="pic-desc">说明五</div><div><img src="f.jpg"></div><div class="pic-desc">说明六</div></div>` +
+		strings.Repeat(paragraph, 3) + `<p><img src="g.jpg" alt="没有说明"></p>` + paragraph + `</article></body></html>`
+	article, err := New(Option{PageURL: "http://example.com/news/1.html"}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, figure := range []string{
+		`<figure><img src="http://example.com/news/a.jpg" alt="图一"/><figcaption>说明一</figcaption></figure>`,
+		`<figure><a href="http://example.com/news/big.jpg"><img src="http://example.com/news/b.jpg"/></a><figcaption>说明二</figcaption></figure>`,
+		`<figure><img src="http://example.com/news/c.jpg"/><figcaption>说明三</figcaption></figure>`,
+		`<figure><img src="http://example.com/news/d.jpg"/><figcaption>说明四</figcaption></figure>`,
+		`<figure><img src="http://example.com/news/e.jpg"/><figcaption>说明五</figcaption></figure>`,
+		`<figure><img src="http://example.com/news/f.jpg"/><figcaption>说明六</figcaption></figure>`,
+	} {
+		if !strings.Contains(article.Content, figure) {
+			t.Errorf("正文中没有 %s", figure)
+		}
+	}
+	if t.Failed() {
+		t.Log(article.Content)
+	}
+	images := []Image{
+		{Src: "http://example.com/news/a.jpg", Alt: "图一", Caption: "说明一"},
+		{Src: "http://example.com/news/b.jpg", Caption: "说明二"},
+		{Src: "http://example.com/news/c.jpg", Caption: "说明三"},
+		{Src: "http://example.com/news/d.jpg", Caption: "说明四"},
+		{Src: "http://example.com/news/e.jpg", Caption: "说明五"},
+		{Src: "http://example.com/news/f.jpg", Caption: "说明六"},
+		{Src: "http://example.com/news/g.jpg", Alt: "没有说明"},
+	}
+	if !reflect.DeepEqual(article.Images, images) {
+		t.Errorf("Images = %+v", article.Images)
+	}
+}
+
+func TestFiguresIgnoreDetachedCaptions(t *testing.T) {
+	cases := []string{
+		// 说明前是文字段落
+		`<p>正文</p><p class="caption">说明</p>`,
+		// 容器中还有其他文字
+		`<div><img src="a.jpg"><p>另一段文字</p><p class="caption">说明</p></div>`,
+		// 说明过长
+		`<p><img src="a.jpg"></p><p class="caption">` + strings.Repeat("很长的说明", 50) + `</p>`,
+	}
+	for _, c := range cases {
+		read := New(Option{})
+		if _, err := read.Parse("<html><body>" + c + "</body></html>"); err != nil {
+			t.Fatal(err)
+		}
+		if read.dom.Find("figure").Length() > 0 {
+			h, _ := read.dom.Html()
+			t.Errorf("%s\n不应整理为 figure：%s", c, h)
+		}
+	}
+}
@@ -0,0 +1,475 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 脚注：脚注列表常位于正文之外，class 又含 foot、footnote 等字样，会在评分前被当作垃圾删除。
+// 这里在评分之前找出脚注标记与对应的条目，把条目从文档中取出，提取正文后再以统一的格式
+// 追加到正文末尾：
+//
+//	<p>正文<sup id="fnref-1"><a href="#fn-1">1</a></sup></p>
+//	<section class="footnotes"><ol>
+//	  <li id="fn-1">脚注内容 <a href="#fnref-1">↩</a></li>
+//	</ol></section>
+
+const maxFootnoteLength = 2000
+
+var (
+	// 链接形式的脚注标记的文字，如 1、[1]、注1、*
+	footnoteRefTextPattern = regexp.MustCompile(`^[\[［(（]?(?:注\s*)?\d{1,3}[\]］)）]?$|^[*†‡]$`)
+	// 脚注链接常用的锚点，如 #fn1、#footnote-1、#cite_note-1
+	footnoteHrefPattern = regexp.MustCompile(`(?i)^#(?:fn|foot|note|endnote|cite|ref)`)
+	// 文字形式的脚注标记：[1]、［1］、（注1）、注1。不带括号的注1 不能紧跟在汉字后面，以免匹配“关注1”
+	footnoteMarkerPattern = regexp.MustCompile(`\[(\d{1,3})\]|［(\d{1,3})］|[\[［(（]注\s*(\d{1,3})[\]］)）]|注(\d{1,3})`)
+	// 脚注条目开头的编号，与 footnoteMarkerPattern 的形式对应，注1 后须有分隔符，以免正文段落被当作条目
+	footnoteEntryPattern = regexp.MustCompile(`^\s*(?:\[(\d{1,3})\]|［(\d{1,3})］|注\s*(\d{1,3})(?:[：:.、]|\s))\s*[：:.、]?\s*`)
+	// 返回正文的链接的文字
+	footnoteBackrefPattern = regexp.MustCompile(`^(?:↩[\x{FE0E}\x{FE0F}]?|↑|\^|返回)$`)
+	// 脚注列表前的小标题
+	footnoteHeadingPattern = regexp.MustCompile(`(?i)^(?:注释?|脚注|尾注|参考文献|参考资料|notes|footnotes|endnotes|references)[:：]?$`)
+	// 文字标记不会出现在这些元素中
+	footnoteSkipTags = map[string]bool{"a": true, "pre": true, "code": true, "sup": true, "textarea": true}
+)
+
+type footnote struct {
+	number int
+	entry  *html.Node
+	// 正文中指向该脚注的标记
+	refs int
+}
+
+// 找出脚注标记与条目，将标记替换为统一的链接，条目从文档中取出保存在 read.footnotes 中。
+// 需在评分之前调用。
+func (read *Readability) extractFootnotes() {
+	read.footnotes = nil
+	body := read.dom.Find("body").First()
+	if body.Length() == 0 {
+		return
+	}
+	root := body.Get(0)
+
+	ids := make(map[string]*html.Node)
+	body.Find("[id], a[name]").Each(func(i int, s *goquery.Selection) {
+		if id, has := s.Attr("id"); has {
+			if _, dup := ids[id]; !dup {
+				ids[id] = s.Get(0)
+			}
+		}
+		if name, has := s.Attr("name"); has {
+			if _, dup := ids[name]; !dup {
+				ids[name] = s.Get(0)
+			}
+		}
+	})
+
+	// 链接形式：<sup><a href="#fn1">1</a></sup>
+	linked := make(map[*html.Node]*html.Node)
+	entries := make(map[*html.Node]bool)
+	body.Find(`a[href^="#"]`).Each(func(i int, a *goquery.Selection) {
+		if !isFootnoteRef(a) {
+			return
+		}
+		target, err := url.PathUnescape(a.AttrOr("href", "")[1:])
+		if err != nil {
+			return
+		}
+		if entry := footnoteEntry(ids[target], a.Get(0)); entry != nil {
+			linked[a.Get(0)] = entry
+			entries[entry] = true
+		}
+	})
+
+	// 文字形式：正文中的 [1]、注1 与以相同编号开头的条目
+	numbered := make(map[int]*html.Node)
+	body.Find("p, li, dd, div").Each(func(i int, s *goquery.Selection) {
+		n := s.Get(0)
+		if hasBlockDescendant(n) {
+			return
+		}
+		for p := n; p != nil; p = p.Parent {
+			if entries[p] {
+				return
+			}
+		}
+		m := footnoteEntryPattern.FindStringSubmatch(s.Text())
+		if m == nil || textLength(s.Text()) > maxFootnoteLength {
+			return
+		}
+		// 重复的编号以后出现的为准，脚注通常在文末
+		numbered[footnoteNumber(m)] = n
+	})
+	for _, n := range numbered {
+		entries[n] = true
+	}
+
+	var notes []*footnote
+	byEntry := make(map[*html.Node]*footnote)
+	ref := func(entry *html.Node) *html.Node {
+		fn := byEntry[entry]
+		if fn == nil {
+			fn = &footnote{number: len(notes) + 1, entry: entry}
+			notes = append(notes, fn)
+			byEntry[entry] = fn
+		}
+		fn.refs++
+		id := "fnref-" + strconv.Itoa(fn.number)
+		if fn.refs > 1 {
+			id += "-" + strconv.Itoa(fn.refs)
+		}
+		sup := &html.Node{Type: html.ElementNode, Data: "sup", DataAtom: atom.Sup, Attr: []html.Attribute{{Key: "id", Val: id}}}
+		a := &html.Node{Type: html.ElementNode, Data: "a", DataAtom: atom.A, Attr: []html.Attribute{{Key: "href", Val: "#fn-" + strconv.Itoa(fn.number)}}}
+		a.AppendChild(&html.Node{Type: html.TextNode, Data: strconv.Itoa(fn.number)})
+		sup.AppendChild(a)
+		return sup
+	}
+
+	// 按文档顺序编号并替换标记
+	for n := root.FirstChild; n != nil; {
+		if n.Type == html.ElementNode && entries[n] {
+			n = nextNodeWithin(n, root, false)
+			continue
+		}
+		var entry *html.Node
+		if n.Type == html.ElementNode && n.Data == "sup" {
+			text := strings.TrimSpace(textOf(n))
+			if a := onlyElementChild(n); a != nil && linked[a] != nil && strings.TrimSpace(textOf(a)) == text {
+				// 只含脚注链接的 sup 一并替换
+				entry = linked[a]
+			} else if m := footnoteMarkerPattern.FindStringSubmatch(text); m != nil && m[0] == text {
+				// 不带链接的 <sup>[1]</sup>
+				entry = numbered[footnoteNumber(m)]
+			}
+		} else {
+			entry = linked[n]
+		}
+		if entry != nil {
+			next := nextNodeWithin(n, root, false)
+			n.Parent.InsertBefore(ref(entry), n)
+			n.Parent.RemoveChild(n)
+			n = next
+			continue
+		}
+		if n.Type == html.ElementNode && footnoteSkipTags[n.Data] {
+			n = nextNodeWithin(n, root, false)
+			continue
+		}
+		if n.Type == html.TextNode && n.Parent != nil && len(numbered) > 0 {
+			n = replaceFootnoteMarkers(n, numbered, ref)
+		}
+		n = nextNodeWithin(n, root, true)
+	}
+
+	if len(notes) == 0 {
+		return
+	}
+	section := &html.Node{Type: html.ElementNode, Data: "section", DataAtom: atom.Section,
+		Attr: []html.Attribute{{Key: "class", Val: "footnotes"}}}
+	ol := &html.Node{Type: html.ElementNode, Data: "ol", DataAtom: atom.Ol}
+	section.AppendChild(ol)
+	var headings []*html.Node
+	for _, fn := range notes {
+		li, prev := read.footnoteItem(fn, ids)
+		ol.AppendChild(li)
+		if prev != nil {
+			headings = append(headings, prev)
+		}
+	}
+	// 脚注列表被取出后，删除其后不再有内容的小标题
+	for _, h := range headings {
+		if h.Parent == nil || !footnoteHeadingPattern.MatchString(normalizeSpace(ts(textOf(h)))) {
+			continue
+		}
+		if next := nextElement(h.NextSibling); next == nil || headingLevel(next) > 0 {
+			h.Parent.RemoveChild(h)
+		}
+	}
+	read.footnotes = section
+}
+
+// 链接文字像编号，且位于 sup 中或锚点像脚注
+func isFootnoteRef(a *goquery.Selection) bool {
+	href := a.AttrOr("href", "")
+	if len(href) < 2 {
+		return false
+	}
+	if a.AttrOr("role", "") == "doc-noteref" {
+		return true
+	}
+	if !footnoteRefTextPattern.MatchString(normalizeSpace(ts(a.Text()))) {
+		return false
+	}
+	return a.ParentsFiltered("sup").Length() > 0 || a.Find("sup").Length() > 0 || footnoteHrefPattern.MatchString(href)
+}
+
+// 锚点指向的脚注条目，锚点为行内元素时取包含它的段落或列表项
+func footnoteEntry(target, marker *html.Node) *html.Node {
+	for n := target; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode || n.Data == "body" {
+			return nil
+		}
+		if n.Data == "sup" {
+			// 指向另一个标记
+			return nil
+		}
+		switch n.Data {
+		case "li", "p", "dd", "div", "aside", "section":
+			if contains(n, marker) || contains(marker, n) {
+				return nil
+			}
+			if textLength(textOf(n)) == 0 || textLength(textOf(n)) > maxFootnoteLength {
+				return nil
+			}
+			return n
+		}
+	}
+	return nil
+}
+
+// 将 text 中的文字标记替换为脚注链接，返回替换后的最后一个节点
+func replaceFootnoteMarkers(text *html.Node, numbered map[int]*html.Node, ref func(entry *html.Node) *html.Node) *html.Node {
+	last := text
+	for {
+		var entry *html.Node
+		var loc []int
+		for _, m := range footnoteMarkerPattern.FindAllStringSubmatchIndex(last.Data, -1) {
+			if m[8] >= 0 {
+				if r, _ := utf8.DecodeLastRuneInString(last.Data[:m[0]]); unicode.Is(unicode.Han, r) {
+					continue
+				}
+			}
+			if e := numbered[footnoteNumberAt(last.Data, m)]; e != nil {
+				entry, loc = e, m
+				break
+			}
+		}
+		if entry == nil {
+			return last
+		}
+		after := &html.Node{Type: html.TextNode, Data: last.Data[loc[1]:]}
+		last.Data = last.Data[:loc[0]]
+		sup := ref(entry)
+		last.Parent.InsertBefore(sup, last.NextSibling)
+		last.Parent.InsertBefore(after, sup.NextSibling)
+		last = after
+	}
+}
+
+func footnoteNumber(m []string) int {
+	for _, s := range m[1:] {
+		if len(s) > 0 {
+			n, _ := strconv.Atoi(s)
+			return n
+		}
+	}
+	return 0
+}
+
+func footnoteNumberAt(s string, loc []int) int {
+	for i := 2; i+1 < len(loc); i += 2 {
+		if loc[i] >= 0 {
+			n, _ := strconv.Atoi(s[loc[i]:loc[i+1]])
+			return n
+		}
+	}
+	return 0
+}
+
+// 取出条目的内容作为 <li>，去掉开头的编号与原有的返回链接，并删除变空的容器。
+// 同时返回被删除部分前面的元素，可能是脚注列表的小标题
+func (read *Readability) footnoteItem(fn *footnote, ids map[string]*html.Node) (*html.Node, *html.Node) {
+	li := &html.Node{Type: html.ElementNode, Data: "li", DataAtom: atom.Li,
+		Attr: []html.Attribute{{Key: "id", Val: "fn-" + strconv.Itoa(fn.number)}}}
+	entry := fn.entry
+	goquery.NewDocumentFromNode(entry).Find(`a[href^="#"]`).Each(func(i int, a *goquery.Selection) {
+		text := normalizeSpace(ts(a.Text()))
+		target := ids[strings.TrimPrefix(a.AttrOr("href", ""), "#")]
+		if footnoteBackrefPattern.MatchString(text) || (target != nil && !contains(entry, target)) {
+			removeEmptyAncestor(a.Get(0), entry)
+		}
+	})
+	// 开头的编号
+	for n := entry.FirstChild; n != nil; n = nextNodeWithin(n, entry, true) {
+		if n.Type != html.TextNode || len(strings.TrimSpace(n.Data)) == 0 {
+			continue
+		}
+		if loc := footnoteEntryPattern.FindStringIndex(n.Data); loc != nil {
+			n.Data = n.Data[loc[1]:]
+		}
+		break
+	}
+	for entry.FirstChild != nil {
+		c := entry.FirstChild
+		entry.RemoveChild(c)
+		li.AppendChild(c)
+	}
+
+	if entry.Parent == nil {
+		return li, nil
+	}
+	removed := entry
+	if empty := removeEmptyAncestors(entry.Parent, entry); empty != nil && empty.Parent != nil {
+		removed = empty
+	}
+	prev := prevElement(removed)
+	removed.Parent.RemoveChild(removed)
+	return li, prev
+}
+
+// 删除 n 并向上删除随之变空的祖先，不超出 stop
+func removeEmptyAncestor(n, stop *html.Node) {
+	for n.Parent != nil && n.Parent != stop && isEmptyElement(n.Parent, n) {
+		n = n.Parent
+	}
+	n.Parent.RemoveChild(n)
+}
+
+// 删除 except 后随之变空的最外层祖先，从 n 开始向上查找，不含 body，没有时返回 nil
+func removeEmptyAncestors(n, except *html.Node) *html.Node {
+	var empty *html.Node
+	for ; n != nil && n.Type == html.ElementNode && n.Data != "body" && isEmptyElement(n, except); n = n.Parent {
+		empty, except = n, n
+	}
+	return empty
+}
+
+// n 中除 except 以外没有文字与图片
+func isEmptyElement(n, except *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c == except {
+			continue
+		}
+		if len(strings.TrimSpace(textOf(c))) > 0 {
+			return false
+		}
+		if c.Type == html.ElementNode && (c.Data == "img" || goquery.NewDocumentFromNode(c).Find("img").Length() > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// 将脚注追加到正文末尾，只保留正文中仍有标记的脚注，返回链接指向第一个标记
+func (read *Readability) appendFootnotes(articleContent *goquery.Selection) {
+	if read.footnotes == nil {
+		return
+	}
+	section := goquery.NewDocumentFromNode(read.footnotes).Selection
+	section.Find("li").Each(func(i int, li *goquery.Selection) {
+		id := li.AttrOr("id", "")
+		marker := articleContent.Find(`a[href="#` + id + `"]`).First().Parent()
+		if marker.Length() == 0 || marker.Get(0).Data != "sup" {
+			li.Remove()
+			return
+		}
+		back := &html.Node{Type: html.ElementNode, Data: "a", DataAtom: atom.A,
+			Attr: []html.Attribute{{Key: "href", Val: "#" + marker.AttrOr("id", "")}}}
+		back.AppendChild(&html.Node{Type: html.TextNode, Data: "↩"})
+		// 内容以段落结尾时放在段落末尾
+		n := li.Get(0)
+		at := n
+		if last := n.LastChild; last != nil && last.Type == html.ElementNode && last.Data == "p" {
+			at = last
+		}
+		if last := at.LastChild; last != nil && last.Type == html.TextNode {
+			last.Data = strings.TrimRightFunc(last.Data, unicode.IsSpace)
+		}
+		at.AppendChild(&html.Node{Type: html.TextNode, Data: " "})
+		at.AppendChild(back)
+	})
+	if section.Find("li").Length() == 0 {
+		return
+	}
+	page := articleContent.Find("#readability-page-1").First()
+	if page.Length() == 0 {
+		page = articleContent
+	}
+	page.Get(0).AppendChild(read.footnotes)
+	read.footnotes = nil
+}
+
+// 将后续分页 page 中的脚注并入 articleContent 中已有的脚注，编号接着已有的脚注，
+// 以免各页的 fn-1 等 id 重复。没有已有的脚注时保留 page 中的脚注
+func mergeFootnotes(articleContent, page *goquery.Selection) {
+	section := page.Find("section.footnotes").First()
+	if section.Length() == 0 {
+		return
+	}
+	merged := articleContent.Find("section.footnotes").First()
+	offset := merged.ChildrenFiltered("ol").ChildrenFiltered("li").Length()
+
+	// 旧编号 -> 新编号
+	numbers := make(map[string]string)
+	items := section.ChildrenFiltered("ol").ChildrenFiltered("li")
+	items.Each(func(i int, li *goquery.Selection) {
+		number := strconv.Itoa(offset + i + 1)
+		numbers[strings.TrimPrefix(li.AttrOr("id", ""), "fn-")] = number
+		li.SetAttr("id", "fn-"+number)
+	})
+	// fnref-2 或 fnref-2-3 中的编号
+	renumber := func(s *goquery.Selection, attr, prefix string) (string, bool) {
+		rest := strings.TrimPrefix(s.AttrOr(attr, ""), prefix)
+		old, suffix := rest, ""
+		if i := strings.Index(rest, "-"); i >= 0 {
+			old, suffix = rest[:i], rest[i:]
+		}
+		number, ok := numbers[old]
+		if ok {
+			s.SetAttr(attr, prefix+number+suffix)
+		}
+		return number, ok
+	}
+	page.Find(`sup[id^="fnref-"]`).Each(func(i int, sup *goquery.Selection) {
+		renumber(sup, "id", "fnref-")
+		a := sup.Find(`a[href^="#fn-"]`).First()
+		if number, ok := renumber(a, "href", "#fn-"); ok {
+			a.SetText(number)
+		}
+	})
+	section.Find(`a[href^="#fnref-"]`).Each(func(i int, a *goquery.Selection) {
+		renumber(a, "href", "#fnref-")
+	})
+
+	if merged.Length() == 0 {
+		return
+	}
+	ol := merged.Find("ol").First().Get(0)
+	for _, li := range items.Nodes {
+		ol.AppendChild(detach(li))
+	}
+	section.Remove()
+}
+
+func contains(ancestor, n *html.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+func textOf(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textOf(c))
+	}
+	return b.String()
+}
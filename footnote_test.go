@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLinkedFootnotes(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的脚注。</p>`
+	page := `<html><body><div class="post"><div class="entry-content">` + strings.Repeat(paragraph, 3) +
+		`<p>第一处引用<sup class="footnote-ref"><a href="#fn:a" id="fnref:a">1</a></sup>，` +
+		`第二处<a href="#fn2" class="footnote-ref" id="fnref2"><sup>2</sup></a>，` +
+		`再次引用<sup><a href="#fn:a">1</a></sup>，以及目录链接<a href="#top">1</a>。</p>` +
+		strings.Repeat(paragraph, 3) + `</div>
+<div class="footnotes"><h3>Notes</h3><hr><ol>
+<li id="fn:a"><p>第一条脚注，参见 <a href="/ref">资料</a>。&nbsp;<a href="#fnref:a" class="reversefootnote">↩</a></p></li>
+<li id="fn2"><p>第二条脚注。<a href="#fnref2">↩︎</a></p></li>
+</ol></div></div></body></html>`
+	article, err := New(Option{PageURL: "http://example.com/post/1.html"}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`第一处引用<sup id="fnref-1"><a href="#fn-1">1</a></sup>，`,
+		`第二处<sup id="fnref-2"><a href="#fn-2">2</a></sup>，`,
+		`再次引用<sup id="fnref-1-2"><a href="#fn-1">1</a></sup>，`,
+		`目录链接<a href="#top">1</a>`,
+		`<section class="footnotes"><ol><li id="fn-1"><p>第一条脚注，参见 <a href="http://example.com/ref">资料</a>。 <a href="#fnref-1">↩</a></p></li>`,
+		`<li id="fn-2"><p>第二条脚注。 <a href="#fnref-2">↩</a></p></li></ol></section>`,
+	} {
+		if !strings.Contains(article.Content, s) {
+			t.Errorf("正文中没有 %s", s)
+		}
+	}
+	if strings.Contains(article.Content, "Notes") || strings.Contains(article.Content, "<hr") {
+		t.Errorf("脚注列表的标题与分隔线应被删除")
+	}
+	if t.Failed() {
+		t.Log(article.Content)
+	}
+}
+
+func TestWikipediaFootnotes(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的参考文献。</p>`
+	page := `<html><body><div id="content">` + paragraph +
+		`<p>引用<sup id="cite_ref-1" class="reference"><a href="#cite_note-1">[1]</a></sup>。</p>` +
+		strings.Repeat(paragraph, 4) +
+		`<h2>参考文献</h2><div class="reflist"><ol class="references">` +
+		`<li id="cite_note-1"><span class="mw-cite-backlink"><b><a href="#cite_ref-1">^</a></b></span> <span class="reference-text">某书，第 1 页。</span></li>` +
+		`</ol></div><h2>外部链接</h2><p>外部链接说明文字。</p></div></body></html>`
+	article, err := New(Option{}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(article.Content, `引用<sup id="fnref-1"><a href="#fn-1">1</a></sup>。`) ||
+		!strings.Contains(article.Content, `<li id="fn-1"> <span>某书，第 1 页。</span> <a href="#fnref-1">↩</a></li>`) {
+		t.Errorf("脚注有误：\n%s", article.Content)
+	}
+	if strings.Contains(article.Content, "<h2 id=\"参考文献\"") || strings.Contains(article.Content, "^") {
+		t.Errorf("空的参考文献标题与原有的返回链接应被删除：\n%s", article.Content)
+	}
+}
+
+func TestTextFootnotes(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的注释。</p>`
+	page := `<html><body><div class="article">` + strings.Repeat(paragraph, 3) +
+		`<p>正文中的标记（注1）与<sup>[2]</sup>，没有对应条目的[3]保持不变。</p>` +
+		`<p>关注1号线的乘客，请注意。注1 另见下文。</p>` +
+		strings.Repeat(paragraph, 3) + `</div>
+<div class="footer"><p>注释：</p><p>注1：第一条注释。</p><p>[2] 第二条注释。</p></div></body></html>`
+	article, err := New(Option{}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`标记<sup id="fnref-1"><a href="#fn-1">1</a></sup>与<sup id="fnref-2"><a href="#fn-2">2</a></sup>，没有对应条目的[3]保持不变。`,
+		// 紧跟在汉字后的注1 是普通文字，开头为“注1”而没有分隔符的段落也不是条目
+		`<p>关注1号线的乘客，请注意。<sup id="fnref-1-2"><a href="#fn-1">1</a></sup> 另见下文。</p>`,
+		`<section class="footnotes"><ol><li id="fn-1">第一条注释。 <a href="#fnref-1">↩</a></li><li id="fn-2">第二条注释。 <a href="#fnref-2">↩</a></li></ol></section>`,
+	} {
+		if !strings.Contains(article.Content, s) {
+			t.Errorf("正文中没有 %s\n%s", s, article.Content)
+		}
+	}
+	if strings.Contains(article.Content, "注释：") {
+		t.Errorf("注释标题应被删除：\n%s", article.Content)
+	}
+}
+
+func TestMultiPageFootnotes(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的脚注。</p>`
+	page := func(n int, next string) string {
+		pager := ""
+		if len(next) > 0 {
+			pager = `<div class="pagination"><a href="` + next + `">下一页</a></div>`
+		}
+		return `<html><body><article><div class="content">` + strings.Repeat(paragraph, 3) +
+			fmt.Sprintf(`<p>第%d页的引用<sup><a href="#fn1">1</a></sup>。</p>`, n) + strings.Repeat(paragraph, 3) +
+			fmt.Sprintf(`<ol class="footnotes"><li id="fn1">第%d页的脚注。</li></ol></div>`, n) + pager + `</article></body></html>`
+	}
+	pages := map[string]string{
+		"http://example.com/a_2.html": page(2, "a_3.html"),
+		"http://example.com/a_3.html": page(3, ""),
+	}
+	fetcher := FetcherFunc(func(ctx context.Context, pageURL string) (string, error) {
+		return pages[pageURL], nil
+	})
+	article, err := New(Option{PageURL: "http://example.com/a.html", Fetcher: fetcher}).Parse(page(1, "a_2.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Pages != 3 {
+		t.Fatalf("Pages = %d，期望 3", article.Pages)
+	}
+	for n := 1; n <= 3; n++ {
+		for _, s := range []string{
+			fmt.Sprintf(`第%d页的引用<sup id="fnref-%d"><a href="#fn-%d">%d</a></sup>。`, n, n, n, n),
+			fmt.Sprintf(`<li id="fn-%d">第%d页的脚注。 <a href="#fnref-%d">↩</a></li>`, n, n, n),
+		} {
+			if !strings.Contains(article.Content, s) {
+				t.Errorf("正文中没有 %s", s)
+			}
+		}
+	}
+	if c := strings.Count(article.Content, `<section class="footnotes">`); c != 1 {
+		t.Errorf("各页的脚注应合并为一处，得到 %d 处", c)
+	}
+	if !strings.Contains(article.Content, `<li id="fn-3">第3页的脚注。 <a href="#fnref-3">↩</a></li></ol></section></div>`) {
+		t.Errorf("脚注应在最后一页的末尾")
+	}
+	if t.Failed() {
+		t.Log(article.Content)
+	}
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 以语料作为种子，保证任意输入都不会导致 Parse 崩溃
+func FuzzParse(f *testing.F) {
+	sources, err := filepath.Glob(filepath.Join("testdata", "corpus", "*", "source.html"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, source := range sources {
+		b, err := os.ReadFile(source)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(b))
+	}
+	for _, seed := range []string{
+		"",
+		"<!-- 注释 -->",
+		"<br><br>",
+		"<p>文字<br><br>更多文字</p>",
+		"<html><body><!-- a --><div><br><br><!-- b --></div></body></html>",
+		"<body><div><p>" + strings.Repeat("正文，内容。", 30) + "</p></div></body>",
+		"<frameset><frame></frameset>",
+		"<svg><p>" + strings.Repeat("text, text. ", 50) + "</p></svg>",
+		"<table><tr><td colspan=x>a</td></tr></table>",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		article, err := New(Option{PageURL: corpusPageURL}).Parse(s)
+		if err == nil && article == nil {
+			t.Fatal("Parse 返回了空结果且没有错误")
+		}
+	})
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	// 订阅源的 MIME 类型
+	feedTypes = map[string]bool{
+		"application/rss+xml":   true,
+		"application/atom+xml":  true,
+		"application/feed+json": true,
+	}
+	printTextPattern = regexp.MustCompile(`(?i)^(打印|打印本页|打印本文|打印此文|打印版|列印|列印本頁|print|print\s+this(\s+page|\s+article)?|printer[\s-]friendly(\s+version)?|printable\s+version)$`)
+	printHrefPattern = regexp.MustCompile(`(?i)([?&](print|printable|printer|view)=(1|true|yes|print)(&|$)|/print(/|\.s?html?$|\.php|\.aspx?|\.jsp|$)|printpage|printable|[?&/_-]print[_-]?friendly)`)
+)
+
+// Feed 网页声明的订阅源
+type Feed struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	// application/rss+xml、application/atom+xml 或 application/feed+json
+	Type string `json:"type"`
+}
+
+// Alternate 网页的其他语言版本
+type Alternate struct {
+	URL string `json:"url"`
+	// hreflang 的值，如 zh-CN、en、x-default
+	Lang string `json:"lang"`
+}
+
+// 网页声明的订阅源、AMP 与打印版地址以及其他语言版本
+type articleLinks struct {
+	feeds      []Feed
+	ampURL     string
+	printURL   string
+	alternates []Alternate
+}
+
+// 读取 <link> 与打印链接，需在预处理之前调用，打印链接所在的工具栏会在提取正文时被删除
+func (read *Readability) getArticleLinks() articleLinks {
+	var links articleLinks
+	seen := make(map[string]bool)
+	read.dom.Find("link[href]").Each(func(i int, s *goquery.Selection) {
+		href := ts(s.AttrOr("href", ""))
+		if len(href) == 0 || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return
+		}
+		href = read.resolveURL(href)
+		rel := strings.Fields(strings.ToLower(s.AttrOr("rel", "")))
+		switch {
+		case inSlice(rel, "amphtml"):
+			if len(links.ampURL) == 0 {
+				links.ampURL = href
+			}
+		case !inSlice(rel, "alternate"):
+		case feedTypes[strings.ToLower(ts(s.AttrOr("type", "")))]:
+			if !seen[href] {
+				seen[href] = true
+				links.feeds = append(links.feeds, Feed{
+					URL:   href,
+					Title: normalizeSpace(ts(s.AttrOr("title", ""))),
+					Type:  strings.ToLower(ts(s.AttrOr("type", ""))),
+				})
+			}
+		case len(ts(s.AttrOr("hreflang", ""))) > 0:
+			links.alternates = append(links.alternates, Alternate{URL: href, Lang: ts(s.AttrOr("hreflang", ""))})
+		case strings.EqualFold(ts(s.AttrOr("media", "")), "print") && len(links.printURL) == 0:
+			links.printURL = href
+		}
+	})
+	if len(links.printURL) == 0 {
+		links.printURL = read.findPrintURL()
+	}
+	return links
+}
+
+// 查找文字为 "打印"、"Print" 或地址形如 ?print=1、/print/ 的同站链接
+func (read *Readability) findPrintURL() string {
+	current, err := url.Parse(read.option.PageURL)
+	if err != nil || (current.Scheme != "http" && current.Scheme != "https") {
+		return ""
+	}
+	var printURL string
+	read.dom.Find("a[href]").EachWithBreak(func(i int, a *goquery.Selection) bool {
+		href := ts(a.AttrOr("href", ""))
+		if len(href) == 0 || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return true
+		}
+		if !printTextPattern.MatchString(normalizeSpace(ts(a.Text()))) && !printHrefPattern.MatchString(href) {
+			return true
+		}
+		u, err := url.Parse(read.resolveURL(href))
+		if err != nil || u.Host != current.Host {
+			return true
+		}
+		u.Fragment = ""
+		if u.String() != current.String() {
+			printURL = u.String()
+		}
+		return len(printURL) == 0
+	})
+	return printURL
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestArticleLinks(t *testing.T) {
+	page := `<html><head><title>链接发现测试</title>
+<link rel="alternate" type="application/rss+xml" title="全部文章" href="/feed.xml">
+<link rel="Alternate" type="application/atom+xml" href="https://example.com/atom.xml">
+<link rel="alternate" type="application/feed+json" href="/feed.json">
+<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+<link rel="alternate" type="text/html" href="/other">
+<link rel="amphtml" href="/amp/post.html">
+<link rel="alternate" hreflang="en" href="https://example.com/en/post.html">
+<link rel="alternate" hreflang="x-default" href="/post.html">
+</head><body>
+<div class="toolbar"><a href="javascript:window.print()">打印</a><a href="/post.html?print=1">打印本页</a></div>
+<article>` + strings.Repeat(`<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文。</p>`, 6) + `</article>
+</body></html>`
+	article, err := New(Option{PageURL: "https://example.com/post.html"}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feeds := []Feed{
+		{URL: "https://example.com/feed.xml", Title: "全部文章", Type: "application/rss+xml"},
+		{URL: "https://example.com/atom.xml", Type: "application/atom+xml"},
+		{URL: "https://example.com/feed.json", Type: "application/feed+json"},
+	}
+	if !reflect.DeepEqual(article.Feeds, feeds) {
+		t.Errorf("Feeds = %+v", article.Feeds)
+	}
+	alternates := []Alternate{
+		{URL: "https://example.com/en/post.html", Lang: "en"},
+		{URL: "https://example.com/post.html", Lang: "x-default"},
+	}
+	if !reflect.DeepEqual(article.Alternates, alternates) {
+		t.Errorf("Alternates = %+v", article.Alternates)
+	}
+	if article.AMPURL != "https://example.com/amp/post.html" {
+		t.Errorf("AMPURL = %q", article.AMPURL)
+	}
+	if article.PrintURL != "https://example.com/post.html?print=1" {
+		t.Errorf("PrintURL = %q", article.PrintURL)
+	}
+}
+
+func TestFindPrintURL(t *testing.T) {
+	cases := []struct {
+		head, body, print string
+	}{
+		{`<link rel="alternate" media="print" href="/print/123">`, `<a href="/p/456">Print</a>`, "http://example.com/print/123"},
+		{"", `<a href="/p/123">Print this article</a>`, "http://example.com/p/123"},
+		{"", `<a class="tool" href="/article/print/123">🖨</a>`, "http://example.com/article/print/123"},
+		{"", `<a href="http://other.com/print/1">打印</a>`, ""},
+		{"", `<a href="#">打印</a><a href="/a.html">Printers we tested</a>`, ""},
+	}
+	for _, c := range cases {
+		read := New(Option{PageURL: "http://example.com/a.html"})
+		var err error
+		read.dom, err = goquery.NewDocumentFromReader(strings.NewReader("<html><head>" + c.head + "</head><body>" + c.body + "</body></html>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := read.getArticleLinks().printURL; p != c.print {
+			t.Errorf("%s%s: 打印地址 %q，期望 %q", c.head, c.body, p, c.print)
+		}
+	}
+}
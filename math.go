@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 数学公式：MathJax、KaTeX 把公式渲染为大量带行内样式的 span，清理样式与属性后无法阅读。
+// 评分之前把渲染结果替换为其中的 MathML，没有 MathML 时保留 TeX 源码：
+//
+//	<math display="block">...</math>
+//	<span class="readability-math">\(E = mc^2\)</span>
+//	<span class="readability-math readability-math-display">\[E = mc^2\]</span>
+
+var (
+	// MathJax v2 在 <script type="math/tex"> 前插入的预览与渲染结果
+	mathJaxRenderedPattern = regexp.MustCompile(`(?:^|\s)MathJax(?:_\w+)?(?:\s|$)`)
+	// MathML 中保留的属性，其余属性在输出前删除
+	mathMLAttributes = map[string]bool{
+		"display": true, "displaystyle": true, "scriptlevel": true, "mathvariant": true, "mathsize": true,
+		"encoding": true, "open": true, "close": true, "separators": true,
+		"notation": true, "linethickness": true, "bevelled": true, "numalign": true, "denomalign": true,
+		"form": true, "fence": true, "separator": true, "stretchy": true, "symmetric": true, "largeop": true,
+		"movablelimits": true, "accent": true, "accentunder": true, "lspace": true, "rspace": true,
+		"width": true, "height": true, "depth": true, "voffset": true, "minsize": true, "maxsize": true,
+		"columnalign": true, "rowalign": true, "columnspan": true, "rowspan": true, "columnlines": true,
+		"rowlines": true, "frame": true, "columnspacing": true, "rowspacing": true, "align": true,
+	}
+)
+
+// 净化时允许的 MathML 元素，不含可以嵌入 HTML 的 annotation-xml 与引用外部资源的 mglyph
+var mathMLTags = map[string]bool{
+	"math": true, "semantics": true, "annotation": true, "mrow": true, "mi": true, "mn": true, "mo": true,
+	"ms": true, "mtext": true, "mspace": true, "msub": true, "msup": true, "msubsup": true, "munder": true,
+	"mover": true, "munderover": true, "mfrac": true, "msqrt": true, "mroot": true, "mstyle": true,
+	"merror": true, "mpadded": true, "mphantom": true, "mfenced": true, "menclose": true, "mtable": true,
+	"mtr": true, "mtd": true, "mlabeledtr": true, "mmultiscripts": true, "mprescripts": true, "none": true,
+}
+
+// 整理公式，需在预处理删除脚本之前调用
+func (read *Readability) replaceMath() {
+	// KaTeX：<span class="katex"><span class="katex-mathml"><math>...</math></span><span class="katex-html">
+	read.dom.Find(".katex").Each(func(i int, katex *goquery.Selection) {
+		if katex.ParentsFiltered(".katex").Length() > 0 {
+			return
+		}
+		math := katex.Find("math").First()
+		if math.Length() == 0 {
+			return
+		}
+		at := katex
+		if display := katex.ParentsFiltered(".katex-display").First(); display.Length() > 0 {
+			at = display
+			math.SetAttr("display", "block")
+		}
+		replaceNode(at.Get(0), detach(math.Get(0)))
+	})
+
+	// MathJax v3：<mjx-container><mjx-math>...</mjx-math><mjx-assistive-mml><math>...</math>
+	read.dom.Find("mjx-container").Each(func(i int, container *goquery.Selection) {
+		math := container.Find("math").First()
+		if math.Length() == 0 {
+			return
+		}
+		if container.AttrOr("display", "") == "true" {
+			math.SetAttr("display", "block")
+		}
+		replaceNode(container.Get(0), detach(math.Get(0)))
+	})
+
+	// MathJax v2：渲染结果之后的 <script type="math/tex; mode=display"> 中是 TeX 源码
+	read.dom.Find(`script[type^="math/tex"]`).Each(func(i int, script *goquery.Selection) {
+		n := script.Get(0)
+		for prev := prevElement(n); prev != nil && mathJaxRenderedPattern.MatchString(getAttr(prev, "class")); prev = prevElement(n) {
+			prev.Parent.RemoveChild(prev)
+		}
+		tex := strings.TrimSpace(script.Text())
+		if len(tex) == 0 {
+			return
+		}
+		span := &html.Node{Type: html.ElementNode, Data: "span", DataAtom: atom.Span}
+		if strings.Contains(script.AttrOr("type", ""), "mode=display") {
+			span.Attr = []html.Attribute{{Key: "class", Val: "readability-math readability-math-display"}}
+			span.AppendChild(&html.Node{Type: html.TextNode, Data: `\[` + tex + `\]`})
+		} else {
+			span.Attr = []html.Attribute{{Key: "class", Val: "readability-math"}}
+			span.AppendChild(&html.Node{Type: html.TextNode, Data: `\(` + tex + `\)`})
+		}
+		replaceNode(n, span)
+	})
+}
+
+func detach(n *html.Node) *html.Node {
+	if n.Parent != nil {
+		n.Parent.RemoveChild(n)
+	}
+	return n
+}
+
+// 以 with 替换 n
+func replaceNode(n, with *html.Node) {
+	n.Parent.InsertBefore(with, n)
+	n.Parent.RemoveChild(n)
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMath(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的公式。</p>`
+	page := `<html><body><article>` + strings.Repeat(paragraph, 3) +
+		// KaTeX 行内公式与行间公式
+		`<p>行内公式<span class="katex"><span class="katex-mathml"><math xmlns="http://www.w3.org/1998/Math/MathML"><semantics><mrow><msup><mi>x</mi><mn>2</mn></msup></mrow><annotation encoding="application/x-tex">x^2</annotation></semantics></math></span><span class="katex-html" aria-hidden="true"><span class="base"><span class="strut" style="height:0.8141em;"></span><span class="mord mathnormal">x</span></span></span></span>。</p>` +
+		`<p><span class="katex-display"><span class="katex"><span class="katex-mathml"><math><semantics><mrow><mi>y</mi></mrow><annotation encoding="application/x-tex">y</annotation></semantics></math></span><span class="katex-html" aria-hidden="true"><span class="mord">y</span></span></span></span></p>` +
+		// MathJax v2
+		`<p>质能方程<span class="MathJax_Preview" style="color: inherit;"></span><span class="MathJax" id="MathJax-Element-1-Frame" style="font-size:116%"><nobr><span class="math"><span class="mi">E</span></span></nobr></span><script type="math/tex" id="MathJax-Element-1">E = mc^2</script>。</p>` +
+		`<div class="MathJax_Display"><span class="MathJax">渲染结果</span></div><script type="math/tex; mode=display">\sum_{i=1}^n i</script>` +
+		// MathJax v3
+		`<p>面积<mjx-container class="MathJax" jax="CHTML"><mjx-math class="MJX-TEX"><mjx-mi>r</mjx-mi></mjx-math><mjx-assistive-mml display="inline"><math><mi>π</mi><msup><mi>r</mi><mn>2</mn></msup></math></mjx-assistive-mml></mjx-container>。</p>` +
+		// 页面中原有的 MathML
+		`<p>分数<math display="inline"><mfrac linethickness="0"><mi>a</mi><mi>b</mi></mfrac></math>。</p>` +
+		strings.Repeat(paragraph, 3) + `</article></body></html>`
+	article, err := New(Option{}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`行内公式<math><semantics><mrow><msup><mi>x</mi><mn>2</mn></msup></mrow><annotation encoding="application/x-tex">x^2</annotation></semantics></math>。`,
+		`<math display="block"><semantics><mrow><mi>y</mi></mrow><annotation encoding="application/x-tex">y</annotation></semantics></math>`,
+		`质能方程<span class="readability-math">\(E = mc^2\)</span>。`,
+		`<span class="readability-math readability-math-display">\[\sum_{i=1}^n i\]</span>`,
+		`面积<math><mi>π</mi><msup><mi>r</mi><mn>2</mn></msup></math>。`,
+		`分数<math display="inline"><mfrac linethickness="0"><mi>a</mi><mi>b</mi></mfrac></math>。`,
+	} {
+		if !strings.Contains(article.Content, s) {
+			t.Errorf("正文中没有 %s", s)
+		}
+	}
+	if strings.Contains(article.Content, "渲染结果") || strings.Contains(article.Content, "katex-html") {
+		t.Errorf("应删除渲染结果")
+	}
+	if t.Failed() {
+		t.Log(article.Content)
+	}
+
+	// 净化后仍保留 MathML
+	article, err = New(Option{Sanitizer: DefaultSanitizePolicy()}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(article.Content, `<math display="inline"><mfrac linethickness="0">`) {
+		t.Errorf("净化后没有 MathML：\n%s", article.Content)
+	}
+}
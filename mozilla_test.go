@@ -0,0 +1,244 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"encoding/json"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Readability.js 测试页面的目录，默认为随仓库提交的部分页面，见 testdata/mozilla/README.md。
+// 也可以指向 Readability.js 仓库中完整的 test/test-pages：
+//
+//	go test -run TestMozillaParity -mozilla-pages ../readability/test/test-pages
+var mozillaPages = flag.String("mozilla-pages", vendoredMozillaPages, "Readability.js 的 test/test-pages 目录")
+
+var vendoredMozillaPages = filepath.Join("testdata", "mozilla")
+
+// 各页面相似度的基线，以页面名为键，由 -update 写入
+var mozillaBaselinePath = filepath.Join("testdata", "mozilla-parity.json")
+
+const (
+	// 正文相似度不低于该值且元数据一致时视为通过
+	parityPassSimilarity = 0.9
+	// 相似度允许低于基线的幅度
+	parityTolerance = 0.005
+)
+
+// Readability.js 的 expected-metadata.json，缺失的字段为 null
+type mozillaMetadata struct {
+	Title   *string `json:"title"`
+	Byline  *string `json:"byline"`
+	Dir     *string `json:"dir"`
+	Excerpt *string `json:"excerpt"`
+}
+
+// 与 Readability.js 的测试页面比对正文与元数据
+func TestMozillaParity(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join(*mozillaPages, "*", "source.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) == 0 {
+		t.Skipf("%s 中没有测试页面，先运行 testdata/mozilla/fetch.sh", *mozillaPages)
+	}
+	// 基线记录每个页面的相似度，防止算法改动后与 Readability.js 的差距扩大
+	baseline := make(map[string]float64)
+	if b, err := os.ReadFile(mozillaBaselinePath); err == nil {
+		if err := json.Unmarshal(b, &baseline); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scores := make(map[string]float64)
+	passed := 0
+	for _, source := range dirs {
+		dir := filepath.Dir(source)
+		name := filepath.Base(dir)
+		t.Run(name, func(t *testing.T) {
+			similarity, mismatches, err := compareMozillaPage(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scores[name] = similarity
+			status := "FAIL"
+			if similarity >= parityPassSimilarity && len(mismatches) == 0 {
+				status = "PASS"
+				passed++
+			}
+			t.Logf("%s 相似度 %.3f %s", status, similarity, strings.Join(mismatches, " "))
+			if *update {
+				return
+			}
+			// 随仓库提交的页面都应有基线
+			if base, has := baseline[name]; !has && *mozillaPages == vendoredMozillaPages {
+				t.Errorf("没有基线，运行 -update 记录")
+			} else if has && similarity < base-parityTolerance {
+				t.Errorf("相似度 %.3f 低于基线 %.3f", similarity, base)
+			}
+		})
+	}
+
+	var sum float64
+	for _, s := range scores {
+		sum += s
+	}
+	t.Logf("通过 %d/%d，平均相似度 %.3f", passed, len(scores), sum/float64(len(scores)))
+
+	if *update {
+		// 只更新本次比对的页面，保留其他页面的基线
+		for name, s := range scores {
+			baseline[name] = math.Floor(s*1000) / 1000
+		}
+		b, err := json.MarshalIndent(baseline, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(mozillaBaselinePath, append(b, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// 比对单个页面，返回正文相似度以及不一致的元数据字段
+func compareMozillaPage(dir string) (float64, []string, error) {
+	source, err := os.ReadFile(filepath.Join(dir, "source.html"))
+	if err != nil {
+		return 0, nil, err
+	}
+	expectedContent, err := os.ReadFile(filepath.Join(dir, "expected.html"))
+	if err != nil {
+		return 0, nil, err
+	}
+	var expected mozillaMetadata
+	b, err := os.ReadFile(filepath.Join(dir, "expected-metadata.json"))
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := json.Unmarshal(b, &expected); err != nil {
+		return 0, nil, err
+	}
+
+	// Readability.js 的测试统一使用该地址
+	article, err := New(Option{PageURL: "http://fakehost/test/page.html"}).Parse(string(source))
+	if err != nil {
+		return 0, nil, err
+	}
+	expectedText, err := htmlText(string(expectedContent))
+	if err != nil {
+		return 0, nil, err
+	}
+	similarity := textSimilarity(expectedText, article.TextContent)
+
+	var mismatches []string
+	check := func(field string, expected *string, actual string) {
+		e := ""
+		if expected != nil {
+			e = normalizeSpace(ts(*expected))
+		}
+		if e != normalizeSpace(ts(actual)) {
+			mismatches = append(mismatches, field)
+		}
+	}
+	check("title", expected.Title, article.Title)
+	check("byline", expected.Byline, article.Byline)
+	check("dir", expected.Dir, article.Dir)
+	check("excerpt", expected.Excerpt, article.Excerpt)
+	sort.Strings(mismatches)
+	return similarity, mismatches, nil
+}
+
+func htmlText(s string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(s))
+	if err != nil {
+		return "", err
+	}
+	return doc.Text(), nil
+}
+
+// 以词的二元组计算 Dice 系数作为正文相似度，中日韩文字按单字切分
+func textSimilarity(a, b string) float64 {
+	ba, bb := tokenBigrams(a), tokenBigrams(b)
+	total := 0
+	for _, c := range ba {
+		total += c
+	}
+	for _, c := range bb {
+		total += c
+	}
+	if total == 0 {
+		return 1
+	}
+	common := 0
+	for k, c := range ba {
+		if d, has := bb[k]; has {
+			if d < c {
+				c = d
+			}
+			common += c
+		}
+	}
+	return 2 * float64(common) / float64(total)
+}
+
+func tokenBigrams(s string) map[string]int {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, strings.ToLower(word.String()))
+			word.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	bigrams := make(map[string]int)
+	if len(tokens) == 1 {
+		bigrams[tokens[0]]++
+	}
+	for i := 1; i < len(tokens); i++ {
+		bigrams[tokens[i-1]+" "+tokens[i]]++
+	}
+	return bigrams
+}
+
+func TestTextSimilarity(t *testing.T) {
+	cases := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"the quick brown fox", "The quick, brown fox!", 1, 1},
+		{"the quick brown fox", "lorem ipsum dolor sit", 0, 0},
+		{"中文正文内容", "中文正文内容", 1, 1},
+		{"one two three four five six", "one two three four", 0.7, 0.8},
+		{"", "", 1, 1},
+	}
+	for _, c := range cases {
+		if s := textSimilarity(c.a, c.b); s < c.min || s > c.max {
+			t.Errorf("textSimilarity(%q, %q) = %.3f，期望 [%.3f, %.3f]", c.a, c.b, s, c.min, c.max)
+		}
+	}
+}
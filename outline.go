@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 生成的锚点最多保留的字符数
+const maxAnchorLength = 64
+
+// Heading 正文大纲中的标题，Children 为其下级标题
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	// 标题元素的 id，可作为锚点链接到正文中的位置
+	ID       string    `json:"id"`
+	Children []Heading `json:"children,omitempty"`
+}
+
+// 调整标题级别，使正文中最高一级的标题为 h2，需在 prepArticle 删除 h1 之前调用。
+// 与文章标题相同的 h1 仍被删除，其余 h1 随之降级而不是删除。
+func (read *Readability) normalizeHeadings(s *goquery.Selection) {
+	s.Find("h1").Each(func(i int, h1 *goquery.Selection) {
+		if read.matchesTitle(h1.Text()) {
+			h1.Remove()
+		}
+	})
+	top := 0
+	headings := s.Find("h1, h2, h3, h4, h5, h6")
+	headings.Each(func(i int, h *goquery.Selection) {
+		if level := headingLevel(h.Get(0)); top == 0 || level < top {
+			top = level
+		}
+	})
+	if top == 0 || top == 2 {
+		return
+	}
+	headings.Each(func(i int, h *goquery.Selection) {
+		level := headingLevel(h.Get(0)) - top + 2
+		if level > 6 {
+			level = 6
+		}
+		n := h.Get(0)
+		n.Data = "h" + strconv.Itoa(level)
+		n.DataAtom = atom.Lookup([]byte(n.Data))
+	})
+}
+
+// h1 至 h6 的级别，其他元素为 0
+func headingLevel(n *html.Node) int {
+	if n.Type != html.ElementNode || len(n.Data) != 2 || n.Data[0] != 'h' || n.Data[1] < '1' || n.Data[1] > '6' {
+		return 0
+	}
+	return int(n.Data[1] - '0')
+}
+
+// 列出正文中的 h2 至 h6 标题，没有 id 或 id 重复的标题写入生成的 id
+func (read *Readability) getOutline(articleContent *goquery.Selection) []Heading {
+	ids := make(map[string]bool)
+	articleContent.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		ids[s.AttrOr("id", "")] = true
+	})
+	used := make(map[string]bool)
+	var flat []Heading
+	articleContent.Find("h2, h3, h4, h5, h6").Each(func(i int, h *goquery.Selection) {
+		text := normalizeSpace(ts(h.Text()))
+		if len(text) == 0 {
+			return
+		}
+		id := ts(h.AttrOr("id", ""))
+		if len(id) == 0 || used[id] {
+			id = uniqueAnchor(headingAnchor(text), ids)
+			h.SetAttr("id", id)
+		}
+		ids[id], used[id] = true, true
+		flat = append(flat, Heading{Level: headingLevel(h.Get(0)), Text: text, ID: id})
+	})
+	return buildOutline(flat)
+}
+
+// 按级别将依次排列的标题组织为树，级别更低的后续标题为前一个标题的下级
+func buildOutline(flat []Heading) []Heading {
+	var outline []Heading
+	for i := 0; i < len(flat); {
+		h := flat[i]
+		j := i + 1
+		for j < len(flat) && flat[j].Level > h.Level {
+			j++
+		}
+		h.Children = buildOutline(flat[i+1 : j])
+		outline = append(outline, h)
+		i = j
+	}
+	return outline
+}
+
+// 由标题文字生成锚点：保留字母与数字（含中文），其余字符合并为连字符
+func headingAnchor(text string) string {
+	var b strings.Builder
+	n, dash := 0, false
+	for _, r := range strings.ToLower(text) {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			dash = true
+			continue
+		}
+		if n >= maxAnchorLength {
+			break
+		}
+		if dash && n > 0 {
+			b.WriteByte('-')
+			n++
+		}
+		dash = false
+		b.WriteRune(r)
+		n++
+	}
+	if b.Len() == 0 {
+		return "section"
+	}
+	return b.String()
+}
+
+// 与正文中已有的 id 重复时加上序号
+func uniqueAnchor(anchor string, ids map[string]bool) string {
+	if !ids[anchor] {
+		return anchor
+	}
+	for i := 1; ; i++ {
+		if a := anchor + "-" + strconv.Itoa(i); !ids[a] {
+			return a
+		}
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOutline(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的标题。</p>`
+	page := `<html><head><title>并发模式</title></head><body><article>` + paragraph +
+		`<h2>Getting Started!</h2>` + paragraph +
+		`<h3>安装</h3>` + paragraph +
+		`<h3 id="usage">用法</h3>` + paragraph +
+		`<h4>示例</h4>` + paragraph +
+		`<h2>安装</h2>` + paragraph +
+		`<h2></h2>` + paragraph + `</article></body></html>`
+	article, err := New(Option{}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Heading{
+		{Level: 2, Text: "Getting Started!", ID: "getting-started", Children: []Heading{
+			{Level: 3, Text: "安装", ID: "安装"},
+			{Level: 3, Text: "用法", ID: "usage", Children: []Heading{
+				{Level: 4, Text: "示例", ID: "示例"},
+			}},
+		}},
+		{Level: 2, Text: "安装", ID: "安装-1"},
+	}
+	if !reflect.DeepEqual(article.Outline, want) {
+		t.Errorf("Outline = %+v", article.Outline)
+	}
+	for _, h := range []string{`<h2 id="getting-started">`, `<h3 id="安装">`, `<h3 id="usage">`, `<h2 id="安装-1">`} {
+		if !strings.Contains(article.Content, h) {
+			t.Errorf("正文中没有 %s：\n%s", h, article.Content)
+		}
+	}
+}
+
+func TestNormalizeHeadings(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的标题。</p>`
+	page := `<html><head><title>并发模式</title></head><body><article><h1>并发模式</h1>` + paragraph +
+		`<h1>生产者与消费者</h1>` + paragraph +
+		`<h3>关闭 channel</h3>` + paragraph +
+		`<h1>超时与取消</h1>` + paragraph + `</article></body></html>`
+
+	// 默认删除所有 h1
+	article, err := New(Option{}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Heading{{Level: 3, Text: "关闭 channel", ID: "关闭-channel"}}
+	if !reflect.DeepEqual(article.Outline, want) {
+		t.Errorf("Outline = %+v", article.Outline)
+	}
+
+	// 与标题相同的 h1 被删除，其余标题降一级
+	article, err = New(Option{NormalizeHeadings: true}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Heading{
+		{Level: 2, Text: "生产者与消费者", ID: "生产者与消费者", Children: []Heading{
+			{Level: 4, Text: "关闭 channel", ID: "关闭-channel"},
+		}},
+		{Level: 2, Text: "超时与取消", ID: "超时与取消"},
+	}
+	if !reflect.DeepEqual(article.Outline, want) {
+		t.Errorf("Outline = %+v", article.Outline)
+	}
+	if strings.Contains(article.Content, "<h1") || strings.Count(article.Content, "并发模式") != 0 {
+		t.Errorf("正文中不应有 h1 与重复的标题：\n%s", article.Content)
+	}
+
+	// 最高一级为 h4 时升为 h2
+	page = `<html><body><article>` + paragraph + `<h4>一</h4>` + paragraph + `<h5>二</h5>` + paragraph + `</article></body></html>`
+	article, err = New(Option{NormalizeHeadings: true}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(article.Content, `<h2 id="一">一</h2>`) || !strings.Contains(article.Content, `<h3 id="二">二</h3>`) {
+		t.Errorf("标题级别有误：\n%s", article.Content)
+	}
+}
+
+func TestHeadingAnchor(t *testing.T) {
+	for text, want := range map[string]string{
+		"Hello, World!": "hello-world",
+		"  第 1 章：开始  ":  "第-1-章-开始",
+		"C++ & Go":      "c-go",
+		"!!!":           "section",
+	} {
+		if got := headingAnchor(text); got != want {
+			t.Errorf("headingAnchor(%q) = %q，期望 %q", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const defaultMaxPages = 10
+
+var (
+	nextPageTextPattern  = regexp.MustCompile(`(?i)^(下一页|下页|下一頁|下一章|next|next\s*page|next\s*[›»>→]+|[›»>→]+)$`)
+	nextPageClassPattern = regexp.MustCompile(`(?i)(^|\s)(next|next-?page|page-?next|pagination-next|pager-next)(\s|$)`)
+	// 形如 xxx.html、xxx_2.html、xxx-2.shtml 的分页地址
+	numberedPagePattern = regexp.MustCompile(`^(.*?)(?:([_-])(\d+))?(\.s?html?)$`)
+)
+
+// Fetcher 获取分页等后续页面的 HTML
+type Fetcher interface {
+	Fetch(ctx context.Context, pageURL string) (string, error)
+}
+
+// FetcherFunc 将普通函数作为 Fetcher 使用
+type FetcherFunc func(ctx context.Context, pageURL string) (string, error)
+
+// Fetch 调用 f(ctx, pageURL)
+func (f FetcherFunc) Fetch(ctx context.Context, pageURL string) (string, error) {
+	return f(ctx, pageURL)
+}
+
+// 查找下一页的地址，需在正文提取前调用，因为分页导航会被当作垃圾节点删除
+func (read *Readability) findNextPageURL() string {
+	current, err := url.Parse(read.option.PageURL)
+	if err != nil || (current.Scheme != "http" && current.Scheme != "https") {
+		return ""
+	}
+	valid := func(href string) string {
+		if len(ts(href)) == 0 || strings.HasPrefix(ts(href), "#") || strings.HasPrefix(ts(href), "javascript:") {
+			return ""
+		}
+		next, err := url.Parse(read.resolveURL(href))
+		if err != nil || next.Host != current.Host {
+			return ""
+		}
+		next.Fragment = ""
+		if next.String() == current.String() {
+			return ""
+		}
+		return next.String()
+	}
+
+	// rel="next"
+	if next := valid(read.dom.Find(`link[rel~="next"], a[rel~="next"]`).First().AttrOr("href", "")); len(next) > 0 {
+		return next
+	}
+
+	// 形如 "下一页"、"Next ›" 的链接，或 class/id 为 next 的链接
+	var next string
+	read.dom.Find("a[href]").EachWithBreak(func(i int, a *goquery.Selection) bool {
+		if nextPageTextPattern.MatchString(normalizeSpace(ts(a.Text()))) ||
+			nextPageClassPattern.MatchString(a.AttrOr("class", "")+" "+a.AttrOr("id", "")) {
+			next = valid(a.AttrOr("href", ""))
+		}
+		return len(next) == 0
+	})
+	if len(next) > 0 {
+		return next
+	}
+
+	// 数字分页：当前页为 xxx.html 或 xxx_N.html 时，页面中存在指向 xxx_N+1.html 的链接
+	m := numberedPagePattern.FindStringSubmatch(current.Path)
+	if m == nil {
+		return ""
+	}
+	separators := []string{"_", "-"}
+	page := 1
+	if len(m[3]) > 0 {
+		separators = []string{m[2]}
+		page, _ = strconv.Atoi(m[3])
+	}
+	for _, sep := range separators {
+		candidate := *current
+		candidate.Path = m[1] + sep + strconv.Itoa(page+1) + m[4]
+		candidate.RawQuery = ""
+		candidate.Fragment = ""
+		read.dom.Find("a[href]").EachWithBreak(func(i int, a *goquery.Selection) bool {
+			if href := valid(a.AttrOr("href", "")); href == candidate.String() {
+				next = href
+			}
+			return len(next) == 0
+		})
+		if len(next) > 0 {
+			return next
+		}
+	}
+	return ""
+}
+
+// 依次获取后续分页，将每页提取出的正文以 readability-page-N 追加到 articleContent
+func (read *Readability) appendNextPages(articleContent *goquery.Selection, firstPageText string) {
+	maxPages := read.option.MaxPages
+	if maxPages <= 0 {
+		maxPages = defaultMaxPages
+	}
+	visited := map[string]bool{read.option.PageURL: true}
+	seen := map[[sha1.Size]byte]bool{sha1.Sum([]byte(firstPageText)): true}
+	next := read.nextPageURL
+	for page := 2; page <= maxPages && len(next) > 0 && !visited[next]; page++ {
+		visited[next] = true
+		read.l("Fetching next page:", next)
+		h, err := read.option.Fetcher.Fetch(read.ctx, next)
+		if err != nil {
+			read.l("Fetch next page failed:", next, err)
+			return
+		}
+		// 以调用方的配置解析，ClassesToPreserve 由 New 复制，不会与本页共用
+		o := read.origin
+		o.PageURL = next
+		o.Fetcher = nil
+		// 只提取第一页的评论
+		o.ExtractComments = false
+		sub := New(o)
+		sub.ctx = read.ctx
+		// 合并的是尚未清除属性的正文，表格等在合并后与第一页一同处理
+		extracted, err := sub.extract(h)
+		if err != nil {
+			read.l("Parse next page failed:", next, err)
+			return
+		}
+		// 内容重复说明已经到了最后一页或者分页地址有误
+		sum := sha1.Sum([]byte(normalizeSpace(extracted.content.Text())))
+		if seen[sum] {
+			read.l("Duplicate page:", next)
+			return
+		}
+		seen[sum] = true
+
+		div := extracted.content.Find("#readability-page-1").First()
+		if div.Length() == 0 {
+			return
+		}
+		for table, isData := range sub.readabilityDataTable {
+			read.readabilityDataTable[table] = isData
+		}
+		div.SetAttr("id", fmt.Sprintf("readability-page-%d", page))
+		mergeFootnotes(articleContent, div)
+		articleContent.AppendNodes(detach(div.Get(0)))
+		read.article.Pages = page
+		next = sub.nextPageURL
+	}
+	// 各页的脚注合并后放在最后一页的末尾
+	if section := articleContent.Find("section.footnotes").First(); section.Length() > 0 && read.article.Pages > 1 {
+		last := articleContent.Find(fmt.Sprintf("#readability-page-%d", read.article.Pages)).First()
+		last.Get(0).AppendChild(detach(section.Get(0)))
+	}
+}
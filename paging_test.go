@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestFindNextPageURL(t *testing.T) {
+	cases := []struct {
+		pageURL, body, next string
+	}{
+		{"http://example.com/a.html", `<link rel="next" href="/a_2.html">`, "http://example.com/a_2.html"},
+		{"http://example.com/a.html", `<a href="a_2.html">下一页</a>`, "http://example.com/a_2.html"},
+		{"http://example.com/post/1", `<a href="?page=2">Next ›</a>`, "http://example.com/post/1?page=2"},
+		{"http://example.com/a.html", `<a class="pager next" href="/p/2">&gt;</a>`, "http://example.com/p/2"},
+		{"http://example.com/n/t20181016_1.htm", `<a href="t20181016_1.htm">1</a><a href="t20181016_2.htm">2</a>`, "http://example.com/n/t20181016_2.htm"},
+		{"http://example.com/n/a.shtml", `<a href="a_2.shtml">2</a>`, "http://example.com/n/a_2.shtml"},
+		{"http://example.com/a.html", `<a href="http://other.com/a_2.html">下一页</a>`, ""},
+		{"http://example.com/a.html", `<a href="#comments">下一页</a>`, ""},
+		{"http://example.com/a.html", `<a class="next-article" href="/b.html">Another story</a>`, ""},
+		{"", `<a href="a_2.html">下一页</a>`, ""},
+	}
+	for _, c := range cases {
+		read := New(Option{PageURL: c.pageURL})
+		var err error
+		read.dom, err = goquery.NewDocumentFromReader(strings.NewReader("<html><body>" + c.body + "</body></html>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next := read.findNextPageURL(); next != c.next {
+			t.Errorf("%s %s: 下一页 %q，期望 %q", c.pageURL, c.body, next, c.next)
+		}
+	}
+}
+
+func testPagedArticle(page int, next string) string {
+	paragraph := fmt.Sprintf("<p>第%d页的正文内容，%s</p>", page,
+		strings.Repeat("这是一段足够长的文字，用来让候选节点获得分数，", 4))
+	pager := ""
+	if len(next) > 0 {
+		pager = `<div class="pagination"><a href="` + next + `">下一页</a></div>`
+	}
+	return `<html><head><meta charset="utf-8"><title>分页文章的标题示例</title></head><body>
+<article><section class="content">` + strings.Repeat(paragraph, 5) + `</section>` + pager + `</article>
+</body></html>`
+}
+
+func TestParseMultiPage(t *testing.T) {
+	pages := map[string]string{
+		"http://example.com/a_2.html": testPagedArticle(2, "a_3.html"),
+		"http://example.com/a_3.html": testPagedArticle(3, "a_2.html"),
+	}
+	var fetched []string
+	fetcher := FetcherFunc(func(ctx context.Context, pageURL string) (string, error) {
+		fetched = append(fetched, pageURL)
+		if h, has := pages[pageURL]; has {
+			return h, nil
+		}
+		return "", errors.New("not found")
+	})
+	article, err := New(Option{PageURL: "http://example.com/a.html", Fetcher: fetcher}).Parse(testPagedArticle(1, "a_2.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Pages != 3 {
+		t.Errorf("Pages = %d，期望 3", article.Pages)
+	}
+	if len(fetched) != 2 {
+		t.Errorf("获取了 %v，期望只获取第 2、3 页", fetched)
+	}
+	for page := 1; page <= 3; page++ {
+		if !strings.Contains(article.Content, fmt.Sprintf(`id="readability-page-%d"`, page)) {
+			t.Errorf("缺少 readability-page-%d", page)
+		}
+		if !strings.Contains(article.TextContent, fmt.Sprintf("第%d页的正文内容", page)) {
+			t.Errorf("缺少第 %d 页正文", page)
+		}
+	}
+	if strings.Contains(article.Content, "下一页") {
+		t.Errorf("正文中包含分页导航")
+	}
+}
+
+func TestParseMultiPageLimits(t *testing.T) {
+	// 每一页都链接到新的地址，但内容与第 2 页相同
+	fetcher := FetcherFunc(func(ctx context.Context, pageURL string) (string, error) {
+		var page int
+		fmt.Sscanf(pageURL, "http://example.com/a_%d.html", &page)
+		return testPagedArticle(2, fmt.Sprintf("a_%d.html", page+1)), nil
+	})
+	article, err := New(Option{PageURL: "http://example.com/a.html", Fetcher: fetcher}).Parse(testPagedArticle(1, "a_2.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Pages != 2 {
+		t.Errorf("重复页面：Pages = %d，期望 2", article.Pages)
+	}
+
+	fetcher = FetcherFunc(func(ctx context.Context, pageURL string) (string, error) {
+		var page int
+		fmt.Sscanf(pageURL, "http://example.com/a_%d.html", &page)
+		return testPagedArticle(page, fmt.Sprintf("a_%d.html", page+1)), nil
+	})
+	article, err = New(Option{PageURL: "http://example.com/a.html", Fetcher: fetcher, MaxPages: 4}).Parse(testPagedArticle(1, "a_2.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Pages != 4 {
+		t.Errorf("MaxPages：Pages = %d，期望 4", article.Pages)
+	}
+}
+
+func TestParseMultiPageOptions(t *testing.T) {
+	table := `<table summary="各季度的销量"><tr><td>一季度</td><td>10</td></tr><tr><td>二季度</td><td>20</td></tr></table>`
+	page2 := strings.Replace(testPagedArticle(2, ""), `</section>`, table+`<p class="keep">第2页保留的样式</p></section>`, 1)
+	fetcher := FetcherFunc(func(ctx context.Context, pageURL string) (string, error) {
+		return page2, nil
+	})
+	// 预留容量，检查分页解析没有写入调用方的切片
+	preserve := make([]string, 1, 8)
+	preserve[0] = "keep"
+	article, err := New(Option{PageURL: "http://example.com/a.html", Fetcher: fetcher, ClassesToPreserve: preserve}).Parse(testPagedArticle(1, "a_2.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Pages != 2 {
+		t.Fatalf("Pages = %d，期望 2", article.Pages)
+	}
+	// 后续分页从原始网页解析，summary 等属性仍可用于判断数据表格
+	if len(article.Tables) != 1 || len(article.Tables[0].Rows) != 2 || article.Tables[0].Rows[1][0] != "二季度" {
+		t.Errorf("Tables = %+v，期望第 2 页的表格", article.Tables)
+	}
+	if !strings.Contains(article.Content, `<p class="keep">`) {
+		t.Errorf("第 2 页的 ClassesToPreserve 未生效：%s", article.Content)
+	}
+	if extra := preserve[1:cap(preserve)]; strings.Join(extra, "") != "" {
+		t.Errorf("调用方的 ClassesToPreserve 被修改：%q", extra)
+	}
+}
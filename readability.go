@@ -6,20 +6,26 @@
 package readability
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"math"
+	"net/http"
+	"net/url"
 
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/saintfish/chardet"
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/encoding/traditionalchinese"
 	"golang.org/x/text/transform"
@@ -28,7 +34,6 @@ import (
 var (
 	chardetor          = chardet.NewHtmlDetector()
 	titleSplitPattern  = regexp.MustCompile(`([^\|_\-\\\/>»«<]{1,})([\|_\-\\\/>»«<]{1,}[^\|_\-\\\/>»«<]{1,})*`)
-	whitespacePattern  = regexp.MustCompile(`\s{2,}`)
 	defaultTagsToScore = map[string]struct{}{
 		"section": {},
 		"h2":      {},
@@ -58,9 +63,12 @@ var (
 	unlikelyCandidatesPattern   = regexp.MustCompile(`(?i)-ad-|banner|breadcrumbs|combx|comment|community|cover-wrap|disqus|extra|foot|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote`)
 	negativePattern             = regexp.MustCompile(`(?i)hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
 	positivePattern             = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
-	videoLinkPattern            = regexp.MustCompile(`(?i)\/\/(www\.)?((dailymotion|youtube|youtube-nocookie|player\.vimeo|v\.qq)\.com|(archive|upload\.wikimedia)\.org|player\.twitch\.tv)`)
 	sharePattern                = regexp.MustCompile(`(?i)share`)
-	flags                       = map[int]bool{flagStripUnlikely: true, flagCleanConditionally: true, flagWeightClasses: true}
+	// 由本库添加、用于标记嵌入内容、脚注与代码语言等的 class，会保留在输出中
+	taggedClassPattern          = regexp.MustCompile(`^readability-|^footnotes$|^language-[\w+#.-]+$`)
+	hiddenStylePattern          = regexp.MustCompile(`display:\s*none`)
+	metaPropertyPattern         = regexp.MustCompile(`\s*(dc|dcterm|og|twitter)\s*:\s*(author|creator|description|title)\s*`)
+	metaNamePattern             = regexp.MustCompile(`^\s*(?:(dc|dcterm|og|twitter|weibo:(article|webpage))\s*[\.:]\s*)?(author|creator|description|title)\s*$`)
 	presentationalAttributes    = []string{"align", "background", "bgcolor", "border", "cellpadding", "cellspacing", "frame", "hspace", "rules", "style", "valign", "vspace"}
 	deprecatedSizeAttributeElem = []string{"table", "th", "td", "hr", "pre"}
 	// 注释掉的元素符合短语内容，但在放入段落时往往会因可读性而被删除，所以我们在此忽略它们。
@@ -74,6 +82,28 @@ var (
 		"sup", "textarea", "time", "var", "wbr",
 	}
 	classesToPreserve = []string{"page"}
+	// 初始化节点分数时各标签的基础分，与 Mozilla Readability 保持一致
+	defaultTagWeights = map[string]float64{
+		"div":        5,
+		"pre":        3,
+		"td":         3,
+		"blockquote": 3,
+		"address":    -3,
+		"ol":         -3,
+		"ul":         -3,
+		"dl":         -3,
+		"dd":         -3,
+		"dt":         -3,
+		"li":         -3,
+		"form":       -3,
+		"h1":         -5,
+		"h2":         -5,
+		"h3":         -5,
+		"h4":         -5,
+		"h5":         -5,
+		"h6":         -5,
+		"th":         -5,
+	}
 )
 
 const (
@@ -91,56 +121,128 @@ type Option struct {
 	CharThreshold     int
 	PageURL           string
 	ClassesToPreserve []string
+	// 标签基础分，覆盖默认值中的同名标签，未列出的标签沿用默认值
+	TagWeights map[string]float64
+	// 用于获取后续分页，为空时不合并分页
+	Fetcher Fetcher
+	// 最多合并的页数（含第一页）
+	MaxPages int
+	// 以下用于 ParseURL 获取网页，HTTPClient 为空时使用 http.DefaultClient
+	HTTPClient  *http.Client
+	UserAgent   string
+	MaxBodySize int64
+	Timeout     time.Duration
+	// 不为空时按白名单净化正文，见 DefaultSanitizePolicy
+	Sanitizer *SanitizePolicy
+	// 允许保留的 iframe、embed、object 来源。为 nil 时使用 DefaultEmbedProviders，
+	// 不为 nil 的空切片表示不保留任何嵌入内容
+	EmbedProviders []EmbedProvider
+	// 调整正文中标题的级别，使最高一级为 h2；与文章标题不同的 h1 降级保留而不是删除
+	NormalizeHeadings bool
+	// 单独提取评论区中的评论到 Article.Comments，不影响正文
+	ExtractComments bool
 }
 
 type metadata struct {
 	Title   string
 	Excerpt string
 	Byline  string
+	Authors []Author
 }
 
 //Readability 网页正文提取
 type Readability struct {
 	article              *Article
 	option               *Option
+	origin               Option // 调用方传入的配置，用于解析后续分页
 	scoreList            map[*html.Node]float64
 	readabilityDataTable map[*html.Node]bool
+	textStats            map[*html.Node]textStats
 	attempts             []*goquery.Selection
+	flags                map[int]bool
+	nextPageURL          string
+	footnotes            *html.Node // 评分前取出的脚注，见 extractFootnotes
+	charset              string
+	ctx                  context.Context
 
 	dom *goquery.Document
 }
 
 //Article 解析结果
 type Article struct {
-	URL         string
-	Title       string
-	Byline      string
-	Dir         string
-	Content     string
-	TextContent string
-	Length      int
-	Excerpt     string
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Byline      string   `json:"byline"`
+	Authors     []Author `json:"authors,omitempty"`
+	Source      string   `json:"source,omitempty"`
+	Editor      string   `json:"editor,omitempty"`
+	Dir         string   `json:"dir,omitempty"`
+	Content     string   `json:"content"`
+	TextContent string   `json:"textContent"`
+	Length      int      `json:"length"`
+	Excerpt     string   `json:"excerpt"`
+	Pages       int      `json:"pages"`
+	// 网页 <head> 中声明的订阅源、AMP 版本、打印版本与其他语言版本
+	Feeds      []Feed      `json:"feeds,omitempty"`
+	AMPURL     string      `json:"ampURL,omitempty"`
+	PrintURL   string      `json:"printURL,omitempty"`
+	Alternates []Alternate `json:"alternates,omitempty"`
+	// 正文中保留的视频等嵌入内容
+	Embeds []Embed `json:"embeds,omitempty"`
+	// 正文中的图片及说明
+	Images []Image `json:"images,omitempty"`
+	// 正文中的数据表格
+	Tables []Table `json:"tables,omitempty"`
+	// 正文中 h2 至 h6 标题组成的大纲，各标题的 id 可作为目录的锚点
+	Outline []Heading `json:"outline,omitempty"`
+	// 评论区中的评论，需开启 Option.ExtractComments
+	Comments []Comment `json:"comments,omitempty"`
 }
 
 //New 新建一个对象
 func New(o Option) *Readability {
+	origin := o
 	if o.NbTopCandidates == 0 {
 		o.NbTopCandidates = 5
 	}
 	if o.CharThreshold == 0 {
 		o.CharThreshold = defaultCharThreshold
 	}
-	o.ClassesToPreserve = append(o.ClassesToPreserve, classesToPreserve...)
+	o.ClassesToPreserve = append(append([]string(nil), o.ClassesToPreserve...), classesToPreserve...)
+	tagWeights := make(map[string]float64, len(defaultTagWeights)+len(o.TagWeights))
+	for tag, weight := range defaultTagWeights {
+		tagWeights[tag] = weight
+	}
+	for tag, weight := range o.TagWeights {
+		tagWeights[tag] = weight
+	}
+	o.TagWeights = tagWeights
 	return &Readability{article: new(Article),
 		scoreList:            make(map[*html.Node]float64),
 		readabilityDataTable: make(map[*html.Node]bool),
 		attempts:             make([]*goquery.Selection, 0),
+		flags:                map[int]bool{flagStripUnlikely: true, flagCleanConditionally: true, flagWeightClasses: true},
+		ctx:                  context.Background(),
 		option:               &o,
+		origin:               origin,
 	}
 }
 
-//编码转换
-func convertCharset(htmlStr string) (string, error) {
+//编码转换，hint 为 HTTP 响应头等处声明的编码，为空时自动识别
+func convertCharset(htmlStr string, hint string) (string, error) {
+	if len(hint) > 0 {
+		if enc, name := charset.Lookup(hint); enc != nil {
+			if name == "utf-8" {
+				return htmlStr, nil
+			}
+			b, err := ioutil.ReadAll(transform.NewReader(strings.NewReader(htmlStr), enc.NewDecoder()))
+			return string(b), err
+		}
+	}
+	// 合法的 UTF-8 无需识别，避免纯 ASCII 页面被识别为 ISO-8859-1
+	if utf8.ValidString(htmlStr) {
+		return htmlStr, nil
+	}
 	res, err := chardetor.DetectBest([]byte(htmlStr))
 	if err != nil {
 		return "", err
@@ -155,13 +257,31 @@ func convertCharset(htmlStr string) (string, error) {
 		b, err := ioutil.ReadAll(transform.NewReader(strings.NewReader(htmlStr), traditionalchinese.Big5.NewDecoder()))
 		return string(b), err
 	}
+	if enc, _ := charset.Lookup(res.Charset); enc != nil {
+		b, err := ioutil.ReadAll(transform.NewReader(strings.NewReader(htmlStr), enc.NewDecoder()))
+		return string(b), err
+	}
 	return "", errors.New("Unsupported charset")
 }
 
-//Parse 进行解析
-func (read *Readability) Parse(s string) (*Article, error) {
+// ParseContext 进行解析，ctx 用于获取后续分页，ctx 结束后在下一次尝试提取正文前停止解析
+func (read *Readability) ParseContext(ctx context.Context, s string) (*Article, error) {
+	read.ctx = ctx
+	return read.Parse(s)
+}
+
+// 提取出的单个页面
+type extraction struct {
+	content  *goquery.Selection
+	md       metadata
+	links    articleLinks
+	comments []Comment
+}
+
+// 解析单个页面，返回经过后期处理、尚未清除属性的正文，供 Parse 与合并后续分页使用
+func (read *Readability) extract(s string) (*extraction, error) {
 	var err error
-	s, err = convertCharset(s)
+	s, err = convertCharset(s, read.charset)
 	if err != nil {
 		return nil, err
 	}
@@ -174,27 +294,73 @@ func (read *Readability) Parse(s string) (*Article, error) {
 	if read.option.MaxNodeNum > 0 && len(read.dom.Nodes) > read.option.MaxNodeNum {
 		return nil, fmt.Errorf("Node 数量超出最大限制：%d 。 ", read.option.MaxNodeNum)
 	}
+	// JSON-LD 位于 script 标签中，需在预处理之前读取
+	jsonLDAuthors := read.getJSONLDAuthors()
+	// 订阅源等链接与打印链接同样需在修改文档之前读取
+	links := read.getArticleLinks()
+	// 评论区会在提取正文时被删除
+	var comments []Comment
+	if read.option.ExtractComments {
+		comments = read.getComments()
+	}
+
 	// 预处理HTML文档以提高可读性。 这包括剥离JavaScript，CSS和处理没用的标记等内容。
 	read.prepDocument()
 
 	// 获取文章的摘要和作者信息
 	md := read.getArticleMetadata()
+	md.Authors = jsonLDAuthors
+
+	// 分页导航会在提取正文时被删除，需提前找出下一页
+	read.nextPageURL = read.findNextPageURL()
 	read.article.Title = md.Title
 
 	// 提取文章正文
 	articleContent := read.grabArticle()
 
+	if err := read.ctx.Err(); err != nil {
+		return nil, err
+	}
 	if articleContent == nil {
 		return nil, errors.New("没能获取到主体")
 	}
 	oh, _ := goquery.OuterHtml(articleContent)
 	read.l("Grabbed: ", oh)
 
+	// 追加脚注，其中的链接同样需要后期处理
+	read.appendFootnotes(articleContent)
+
 	// 后期处理
 	read.postProcessContent(articleContent)
 
+	return &extraction{content: articleContent, md: md, links: links, comments: comments}, nil
+}
+
+//Parse 进行解析
+func (read *Readability) Parse(s string) (*Article, error) {
+	page, err := read.extract(s)
+	if err != nil {
+		return nil, err
+	}
+	articleContent, md, links := page.content, page.md, page.links
+
+	// 获取并合并后续分页
+	read.article.Pages = 1
+	if read.option.Fetcher != nil && len(read.nextPageURL) > 0 {
+		read.appendNextPages(articleContent, normalizeSpace(articleContent.Text()))
+	}
+
+	// 数据表格的判断依赖 summary 等属性，需在清除属性之前提取
+	read.article.Tables = read.getTables(articleContent)
+
 	// 清除所有注释和未使用的属性
-	removeCommentsAndUnusedAttr(articleContent.Get(0))
+	removeCommentsAndUnusedAttr(articleContent.Get(0), read.option.ClassesToPreserve)
+	if read.option.Sanitizer != nil {
+		read.option.Sanitizer.sanitizeChildren(articleContent.Get(0))
+	}
+	read.article.Embeds = read.getEmbeds(articleContent)
+	read.article.Images = read.getImages(articleContent)
+	read.article.Outline = read.getOutline(articleContent)
 
 	// 如果我们没有在文章的元数据中找到摘录，请使用文章的第一段作为摘录。 这用于显示文章内容的预览。
 	if len(md.Excerpt) == 0 {
@@ -204,18 +370,32 @@ func (read *Readability) Parse(s string) (*Article, error) {
 		}
 	}
 
-	read.article.Title = normalizeSpace(md.Title)
+	read.article.Title = normalizeSpace(ts(md.Title))
 	if len(read.article.Byline) > 0 {
 		read.article.Byline = normalizeSpace(read.article.Byline)
 	} else {
 		read.article.Byline = normalizeSpace(md.Byline)
 	}
+	// JSON-LD 中的结构化作者信息优先
+	if len(md.Authors) > 0 {
+		read.article.Authors = md.Authors
+	} else if len(read.article.Authors) == 0 && len(read.article.Byline) > 0 {
+		read.article.Authors = splitByline(read.article.Byline, nil)
+	}
+	if len(read.article.Byline) == 0 && len(read.article.Authors) > 0 {
+		read.article.Byline = joinAuthors(read.article.Authors)
+	}
 	read.article.URL = read.option.PageURL
 	read.article.TextContent = normalizeSpace(articleContent.Text())
 	read.article.Content, err = articleContent.Html()
-	read.article.Content = normalizeSpace(read.article.Content)
+	read.article.Content = normalizeSpaceOutsidePre(read.article.Content)
 	read.article.Length = utf8.RuneCount([]byte(read.article.TextContent))
 	read.article.Excerpt = md.Excerpt
+	read.article.Feeds = links.feeds
+	read.article.AMPURL = links.ampURL
+	read.article.PrintURL = links.printURL
+	read.article.Alternates = links.alternates
+	read.article.Comments = page.comments
 
 	return read.article, err
 }
@@ -225,21 +405,27 @@ func (read *Readability) postProcessContent(articleContent *goquery.Selection) {
 	// Readability 无法打开相关uris，因此我们将它们转换为绝对uris。
 	read.fixRelativeUris(articleContent)
 	// 删除 class
-	cleanClasses(articleContent)
+	read.cleanClasses(articleContent)
 }
 
-// 从给定子树中的每个元素中除去class =“”属性，除了匹配CLASSES_TO_PRESERVE的
-// 元素和来自options对象的classesToPreserve数组。
-func cleanClasses(articleContent *goquery.Selection) {
+// 从给定子树中的每个元素中除去class =“”属性，只保留 CLASSES_TO_PRESERVE 与
+// options 中 ClassesToPreserve 列出的 class。
+func (read *Readability) cleanClasses(articleContent *goquery.Selection) {
 	articleContent.Children().Each(func(i int, sel *goquery.Selection) {
-		cleanClasses(sel)
+		read.cleanClasses(sel)
 		class, has := sel.Attr("class")
-		if has {
-			for _, cls := range classesToPreserve {
-				if strings.Contains(cls, class) {
-					return
-				}
+		if !has {
+			return
+		}
+		var kept []string
+		for _, cls := range strings.Fields(class) {
+			if inSlice(read.option.ClassesToPreserve, cls) || taggedClassPattern.MatchString(cls) {
+				kept = append(kept, cls)
 			}
+		}
+		if len(kept) > 0 {
+			sel.SetAttr("class", strings.Join(kept, " "))
+		} else {
 			sel.RemoveAttr("class")
 		}
 	})
@@ -250,22 +436,20 @@ func (read *Readability) fixRelativeUris(articleContent *goquery.Selection) {
 	if !strings.HasPrefix(read.option.PageURL, "http://") && !strings.HasPrefix(read.option.PageURL, "https://") {
 		read.option.PageURL = "http://" + read.option.PageURL
 	}
-	documentURL := read.option.PageURL[:strings.Index(read.option.PageURL[8:], "/")+8]
-	baseURL, has := read.dom.Find("base").First().Attr("href")
-	if !has || len(ts(baseURL)) == 0 {
-		baseURL = read.option.PageURL[:strings.LastIndex(read.option.PageURL, "/")+1]
+	base := read.option.PageURL
+	if baseHref, has := read.dom.Find("base").First().Attr("href"); has && len(ts(baseHref)) > 0 {
+		base = read.resolveURL(baseHref)
 	}
-	toAbsoluteURI := func(url string) string {
-		if len(url) == 0 {
-			return ""
+	baseURL, baseErr := url.Parse(base)
+	toAbsoluteURI := func(uri string) string {
+		if len(uri) == 0 || strings.HasPrefix(uri, "#") || baseErr != nil {
+			return uri
 		}
-		if strings.HasPrefix(url, "#") || strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "//") {
-			return url
-		} else if strings.HasPrefix(url, "/") {
-			return documentURL + url
-		} else {
-			return baseURL + "/" + url
+		ref, err := url.Parse(ts(uri))
+		if err != nil {
+			return uri
 		}
+		return baseURL.ResolveReference(ref).String()
 	}
 	articleContent.Find("a").Each(func(i int, a *goquery.Selection) {
 		href, has := a.Attr("data-href")
@@ -274,8 +458,13 @@ func (read *Readability) fixRelativeUris(articleContent *goquery.Selection) {
 		}
 		if has {
 			if strings.HasPrefix(href, "javascript:") {
-				a.Get(0).Type = html.TextNode
-				a.Get(0).Data = a.Text()
+				// 替换为链接的文字，需在修改节点类型之前取得文字
+				text := a.Text()
+				n := a.Get(0)
+				for n.FirstChild != nil {
+					n.RemoveChild(n.FirstChild)
+				}
+				n.Type, n.Data, n.Attr = html.TextNode, text, nil
 			} else {
 				a.SetAttr("href", toAbsoluteURI(href))
 			}
@@ -292,6 +481,19 @@ func (read *Readability) fixRelativeUris(articleContent *goquery.Selection) {
 	})
 }
 
+// 以 PageURL 为基准将链接转换为绝对地址
+func (read *Readability) resolveURL(href string) string {
+	ref, err := url.Parse(ts(href))
+	if err != nil {
+		return href
+	}
+	base, err := url.Parse(read.option.PageURL)
+	if err != nil {
+		return ref.String()
+	}
+	return base.ResolveReference(ref).String()
+}
+
 // 提取文章正文
 func (read *Readability) grabArticle() *goquery.Selection {
 	read.l("**** grabArticle ****")
@@ -304,8 +506,13 @@ func (read *Readability) grabArticle() *goquery.Selection {
 	}
 
 	for {
+		if read.ctx.Err() != nil {
+			return nil
+		}
+		// 每次尝试都在新的文档副本上进行，上一次的分数不再需要
+		read.scoreList = make(map[*html.Node]float64)
 		selectionsToScore := make([]*goquery.Selection, 0)
-		stripUnlikelyCandidates := flagIsActive(flagStripUnlikely)
+		stripUnlikelyCandidates := read.flagIsActive(flagStripUnlikely)
 		sel := page.First()
 		for sel != nil {
 			node := sel.Get(0)
@@ -321,6 +528,11 @@ func (read *Readability) grabArticle() *goquery.Selection {
 			}
 
 			// 如果是作者信息 node，删除并将指针移到下一个 node
+			if read.checkTextByline(sel) {
+				read.l("checkTextByline", node.Data, node.Attr)
+				sel = removeAndGetNext(sel)
+				continue
+			}
 			if read.checkByline(sel, matchString) {
 				read.l("checkByline", node.Data, node.Attr)
 				sel = removeAndGetNext(sel)
@@ -358,9 +570,10 @@ func (read *Readability) grabArticle() *goquery.Selection {
 					nextSibling := childNode.NextSibling
 					if read.isPhrasingContent(childNode) {
 						if p != nil {
-							nodeAppendChild(childNode, p, false)
+							nodeAppendChild(childNode, p, true)
 						} else if !read.isWhitespace(childNode) {
-							p = read.createSelection("p").Get(0)
+							p = &html.Node{Type: html.ElementNode, Data: "p", DataAtom: atom.P}
+							node.InsertBefore(p, childNode)
 							nodeAppendChild(childNode, p, true)
 						}
 					} else if p != nil {
@@ -375,8 +588,9 @@ func (read *Readability) grabArticle() *goquery.Selection {
 				// 将只包含一个 p 标签的 div 标签去掉，将 p 提出来
 				if hasSingleTagInsideElement(sel, "p") && getLinkDensity(sel) < 0.25 {
 					next := getNextSelection(sel, true)
-					sel.ReplaceWithSelection(sel.Children().First())
-					selectionsToScore = append(selectionsToScore, sel)
+					child := sel.Children().First()
+					sel.ReplaceWithSelection(child)
+					selectionsToScore = append(selectionsToScore, child)
 					sel = next
 					continue
 				} else if !hasChildBlockElement(sel) {
@@ -394,13 +608,16 @@ func (read *Readability) grabArticle() *goquery.Selection {
 		  分数由 commas，class 名称 等的 数目决定。也许最终链接密度。
 		*/
 		candidates := make([]*goquery.Selection, 0)
+		// 评分和选取兄弟节点期间各节点的子树不再变化，文本统计只需计算一次
+		read.cacheTextStats(page)
 		for _, sel = range selectionsToScore {
 			// 节点或节点的父节点为空，跳过
 			if sel.Parent().Length() == 0 || sel.Length() == 0 {
 				continue
 			}
 			// 如果该段落少于25个字符，跳过
-			if utf8.RuneCountInString(sel.Text()) < 25 {
+			textLen := read.textLengthOf(sel)
+			if textLen < 25 {
 				continue
 			}
 			// 排除没有祖先的节点。
@@ -414,13 +631,11 @@ func (read *Readability) grabArticle() *goquery.Selection {
 			// 为段落本身添加一个基础分
 			contentScore++
 
-			innerText := sel.Text()
 			// 在此段落内为所有逗号添加分数。
-			contentScore += float64(strings.Count(innerText, ","))
-			contentScore += float64(strings.Count(innerText, "，"))
+			contentScore += float64(read.commasOf(sel))
 
 			// 本段中每100个字符添加一分。 最多3分。
-			contentScore += math.Min(float64(utf8.RuneCountInString(innerText)/100), 3)
+			contentScore += math.Min(float64(textLen/100), 3)
 
 			// 给祖先初始化并评分。
 			for level, ancestor := range ancestors {
@@ -457,7 +672,7 @@ func (read *Readability) grabArticle() *goquery.Selection {
 		for _, candidate := range candidates {
 			var candidateScore float64
 			// 根据链接密度缩放最终候选人分数。 良好的内容应该有一个相对较小的链接密度（5％或更少），并且大多不受此操作的影响。
-			candidateScore = read.scoreList[candidate.Get(0)] * (1 - getLinkDensity(candidate))
+			candidateScore = read.scoreList[candidate.Get(0)] * (1 - read.linkDensity(candidate))
 			read.scoreList[candidate.Get(0)] = candidateScore
 
 			read.l("Candidate:", candidate.Get(0).Data, "[", candidate.Get(0).Attr, "]", "with score", candidateScore)
@@ -522,7 +737,7 @@ func (read *Readability) grabArticle() *goquery.Selection {
 			const MinimumTopCandidates = 3
 			if len(alternativeCandidateAncestors) >= MinimumTopCandidates {
 				parentOfTopCandidate = topCandidate.Parent()
-				for parentOfTopCandidate.Get(0).Data != "body" {
+				for !isBodyOrDetached(parentOfTopCandidate) {
 					listsContainingThisAncestor := 0
 					for i := 0; i < len(alternativeCandidateAncestors) && listsContainingThisAncestor < MinimumTopCandidates; i++ {
 						if _, has := alternativeCandidateAncestors[i][topCandidates[i]]; has {
@@ -550,7 +765,7 @@ func (read *Readability) grabArticle() *goquery.Selection {
 			lastScore := read.scoreList[topCandidate.Get(0)]
 			// 分数不能太低。
 			scoreThreshold := lastScore / 3
-			for parentOfTopCandidate.Get(0).Data != "body" {
+			for !isBodyOrDetached(parentOfTopCandidate) {
 				if read.scoreList[parentOfTopCandidate.Get(0)] == 0 {
 					read.initializeScoreSelection(parentOfTopCandidate)
 					continue
@@ -569,11 +784,11 @@ func (read *Readability) grabArticle() *goquery.Selection {
 
 			// 如果最上面的候选人是唯一的孩子，那就用父母代替。 当相邻内容实际位于父节点的兄弟节点中时，这将有助于兄弟连接逻辑。
 			parentOfTopCandidate = topCandidate.Parent()
-			for parentOfTopCandidate.Get(0).Data != "body" && parentOfTopCandidate.Get(0).FirstChild.NextSibling == nil {
+			for !isBodyOrDetached(parentOfTopCandidate) && parentOfTopCandidate.Get(0).FirstChild.NextSibling == nil {
 				topCandidate = parentOfTopCandidate
 				parentOfTopCandidate = topCandidate.Parent()
 			}
-			if read.scoreList[parentOfTopCandidate.Get(0)] == 0 {
+			if parentOfTopCandidate.Length() > 0 && read.scoreList[parentOfTopCandidate.Get(0)] == 0 {
 				read.initializeScoreSelection(parentOfTopCandidate)
 			}
 		}
@@ -608,14 +823,13 @@ func (read *Readability) grabArticle() *goquery.Selection {
 				if read.scoreList[sibling.Get(0)]+contentBonus >= siblingScoreThreshold {
 					willAppend = true
 				} else if sibling.Get(0).Data == "p" {
-					linkDensity := getLinkDensity(sibling)
-					innerText := sibling.Text()
-					textLen := len(innerText)
+					linkDensity := read.linkDensity(sibling)
+					textLen := read.textLengthOf(sibling)
 
 					if textLen > 80 && linkDensity < 0.25 {
 						willAppend = true
 					} else if textLen < 80 && textLen > 0 && linkDensity == 0 &&
-						regexp.MustCompile(`\.( |$)`).MatchString(innerText) {
+						hasSentenceEnd(sibling.Text()) {
 						willAppend = true
 					}
 				}
@@ -623,11 +837,12 @@ func (read *Readability) grabArticle() *goquery.Selection {
 
 			if willAppend {
 				read.l("Appending node:", sibling.Get(0))
-				alter := map[string]int{
+				// 除这几种标签外，其余兄弟节点统一改为 div
+				alterExceptions := map[string]int{
 					"div": 0, "article": 0, "section": 0, "p": 0,
 				}
 				sn := sibling.Get(0)
-				if _, has := alter[sn.Data]; has {
+				if _, has := alterExceptions[sn.Data]; !has {
 					sn.Data = "div"
 					sn.Namespace = "div"
 					read.l("Altering sibling:", sibling.Get(0), "to div.")
@@ -640,6 +855,8 @@ func (read *Readability) grabArticle() *goquery.Selection {
 			}
 		}
 
+		read.clearTextStats()
+
 		logText, _ := goquery.OuterHtml(articleContent)
 		read.l("Article content pre-prep:", logText)
 
@@ -658,14 +875,11 @@ func (read *Readability) grabArticle() *goquery.Selection {
 			div := read.createSelection("div")
 			div.SetAttr("id", "readability-page-1")
 			div.SetAttr("class", "page")
-			ch := articleContent.Get(0).FirstChild
-			dn := div.Get(0)
-			for ch != nil {
-				t := ch.NextSibling
-				nodeAppendChild(ch, dn, false)
-				ch = t
+			an, dn := articleContent.Get(0), div.Get(0)
+			for ch := an.FirstChild; ch != nil; ch = an.FirstChild {
+				nodeAppendChild(ch, dn, true)
 			}
-			articleContent.Get(0).FirstChild = div.Get(0)
+			nodeAppendChild(dn, an, true)
 		}
 
 		logText, _ = goquery.OuterHtml(articleContent)
@@ -675,29 +889,32 @@ func (read *Readability) grabArticle() *goquery.Selection {
 		// 现在我们已经完成了完整的算法，请检查是否有任何有意义的内容。 如果我们没有，我们可能需要
 		// 重新运行具有不同标志的grabArticle。 这使我们更有可能找到内容，而筛选方法使我们更有可
 		// 能找到正确内容。
-		textLength := len(ts(articleContent.Text()))
-		if textLength < read.option.CharThreshold {
+		textLen := textLength(articleContent.Text())
+		if textLen < read.option.CharThreshold {
 			parseSuccessful = false
-			read.dom = originDoc
-			if flagIsActive(flagStripUnlikely) {
-				removeFlag(flagStripUnlikely)
+			// 从原始文档的副本重新开始
+			read.dom = goquery.CloneDocument(originDoc)
+			page = read.dom.Find("body").First()
+			if read.flagIsActive(flagStripUnlikely) {
+				read.removeFlag(flagStripUnlikely)
 				read.attempts = append(read.attempts, articleContent)
-			} else if flagIsActive(flagWeightClasses) {
-				removeFlag(flagWeightClasses)
+			} else if read.flagIsActive(flagWeightClasses) {
+				read.removeFlag(flagWeightClasses)
 				read.attempts = append(read.attempts, articleContent)
-			} else if flagIsActive(flagCleanConditionally) {
-				removeFlag(flagCleanConditionally)
+			} else if read.flagIsActive(flagCleanConditionally) {
+				read.removeFlag(flagCleanConditionally)
 				read.attempts = append(read.attempts, articleContent)
 			} else {
 				bestContent := articleContent
 				for _, c := range read.attempts {
-					if len(ts(bestContent.Text())) < len(ts(c.Text())) {
+					if textLength(bestContent.Text()) < textLength(c.Text()) {
 						bestContent = c
 					}
 				}
-				if len(ts(bestContent.Text())) == 0 {
+				if textLength(bestContent.Text()) == 0 {
 					return nil
 				}
+				articleContent = bestContent
 				parseSuccessful = true
 			}
 		}
@@ -706,7 +923,7 @@ func (read *Readability) grabArticle() *goquery.Selection {
 			as := getSelectionAncestors(parentOfTopCandidate, 0)
 			as = append(as, parentOfTopCandidate, topCandidate)
 			for _, ancestor := range as {
-				if len(ts(ancestor.Get(0).Data)) == 0 {
+				if ancestor.Length() == 0 || len(ts(ancestor.Get(0).Data)) == 0 {
 					continue
 				}
 				dir := ancestor.AttrOr("dir", "")
@@ -747,12 +964,15 @@ func (read *Readability) prepArticle(s *goquery.Selection) {
 	// 清除文章内容中的垃圾
 	read.cleanConditionally(s, "form")
 	read.cleanConditionally(s, "fieldset")
-	clean(s, "object")
-	clean(s, "embed")
-	clean(s, "h1")
-	clean(s, "footer")
-	clean(s, "link")
-	clean(s, "aside")
+	read.clean(s, "object")
+	read.clean(s, "embed")
+	if read.option.NormalizeHeadings {
+		read.normalizeHeadings(s)
+	}
+	read.clean(s, "h1")
+	read.clean(s, "footer")
+	read.clean(s, "link")
+	read.clean(s, "aside")
 
 	// 清理出来的元素在最终候选名单中与他们的id / class组合“共享”，这意味着即使他们有“分享”
 	// ，我们也不会删除顶级候选人。
@@ -763,27 +983,15 @@ func (read *Readability) prepArticle(s *goquery.Selection) {
 	// 如果只有一个h2，并且其文本内容与文章标题大致相同，那么它们可能将其用作标题而不是子标题，因此，
 	// 请将其删除，因为我们已经分别提取标题。
 	h2 := s.Find("h2")
-	if h2.Length() == 1 {
-		h2 = h2.First()
-		lengthSimilarRate := float64(len(h2.Text())-len(read.article.Title)) / float64(len(read.article.Title))
-		if math.Abs(lengthSimilarRate) < 0.5 {
-			var titlesMatch bool
-			if lengthSimilarRate > 0 {
-				titlesMatch = strings.Contains(h2.Text(), read.article.Title)
-			} else {
-				titlesMatch = strings.Contains(read.article.Title, h2.Text())
-			}
-			if titlesMatch {
-				clean(s, "h2")
-			}
-		}
+	if h2.Length() == 1 && read.matchesTitle(h2.Text()) {
+		read.clean(s, "h2")
 	}
 
-	clean(s, "iframe")
-	clean(s, "input")
-	clean(s, "textarea")
-	clean(s, "select")
-	clean(s, "button")
+	read.clean(s, "iframe")
+	read.clean(s, "input")
+	read.clean(s, "textarea")
+	read.clean(s, "select")
+	read.clean(s, "button")
 	read.cleanHeaders(s)
 
 	// 这些最后的东西可能会删除会影响这些东西的垃圾
@@ -837,6 +1045,18 @@ func (read *Readability) prepArticle(s *goquery.Selection) {
 	})
 }
 
+// 文字与文章标题长度相近且互相包含
+func (read *Readability) matchesTitle(text string) bool {
+	lengthSimilarRate := float64(textLength(text)-textLength(read.article.Title)) / float64(textLength(read.article.Title))
+	if math.Abs(lengthSimilarRate) >= 0.5 {
+		return false
+	}
+	if lengthSimilarRate > 0 {
+		return strings.Contains(text, read.article.Title)
+	}
+	return strings.Contains(read.article.Title, text)
+}
+
 // 清除元素中的虚假标题。 检查类名和链接密度。
 func (read *Readability) cleanHeaders(s *goquery.Selection) {
 	for h := 1; h < 3; h++ {
@@ -863,24 +1083,13 @@ func cleanMatchedNodes(s *goquery.Selection, m *regexp.Regexp) {
 }
 
 // 清理“tag”类型的所有元素的节点。（除非它是一个YouTube等的视频，人们喜欢看视频）
-func clean(s *goquery.Selection, tag string) {
+func (read *Readability) clean(s *goquery.Selection, tag string) {
 	embedded := map[string]int{"object": 0, "embed": 0, "iframe": 0}
 	s.Find(tag).Each(func(i int, junk *goquery.Selection) {
-		// 允许youtube和vimeo视频通过人们通常希望看到的视频。
+		// 保留来源在白名单中的视频等嵌入内容，见 Option.EmbedProviders
 		_, isEmbedded := embedded[junk.Get(0).Data]
 		if isEmbedded {
-			var as []string
-			for _, a := range junk.Get(0).Attr {
-				as = append(as, a.Val)
-			}
-			// 首先，检查元素属性，看它们是否包含youtube或vimeo
-			attributeValues := strings.Join(as, "|")
-			if videoLinkPattern.MatchString(attributeValues) {
-				return
-			}
-			// 然后检查这个元素中的元素
-			h, err := junk.Html()
-			if err == nil && videoLinkPattern.MatchString(h) {
+			if _, ok := read.matchEmbedSelection(junk); ok {
 				return
 			}
 		}
@@ -892,12 +1101,19 @@ func clean(s *goquery.Selection, tag string) {
 // 清洁“标签”类型的所有标签的元素，如果它们看起来很腥。
 // “Fishy”是一种基于内容长度，类名，链接密度，图像和嵌入数量等的算法。
 func (read *Readability) cleanConditionally(s *goquery.Selection, tag string) {
-	if !flagIsActive(flagCleanConditionally) {
+	if !read.flagIsActive(flagCleanConditionally) {
 		return
 	}
 	isList := tag == "ul" || tag == "ol"
+	// 按文档顺序处理，祖先节点总在子孙节点被删除之前处理，因此统计结果不会过期
+	read.cacheTextStats(s)
+	defer read.clearTextStats()
 	// 聚集计算嵌入其他典型元素。向后返回，以便我们可以在不影响遍历的情况下同时移除节点。
 	s.Find(tag).Each(func(i int, junk *goquery.Selection) {
+		// 数据表格本身及其中的元素都不清理
+		if tag == "table" && read.readabilityDataTable[junk.Get(0)] {
+			return
+		}
 		if hasAncestorTag(junk, "table", -1, func(s *goquery.Selection) bool {
 			return read.readabilityDataTable[s.Get(0)]
 		}) {
@@ -908,27 +1124,28 @@ func (read *Readability) cleanConditionally(s *goquery.Selection, tag string) {
 		if read.scoreList[junk.Get(0)] < 0 {
 			junk.Remove()
 		}
-		t := junk.Text()
-		if strings.Count(t, ",") < 10 || strings.Count(t, "，") < 10 {
+		if read.commasOf(junk) < 10 {
 			// 如果逗号不多，并且非段落元素的数量多于段落或其他不祥的标志，则删除该元素。
 			p := junk.Find("p").Length()
-			img := junk.Find("img").Length()
+			// figure 中的图片是正文的一部分，不计入图片数
+			figureImg := junk.Find("figure img").Length()
+			img := junk.Find("img").Length() - figureImg
 			li := junk.Find("li").Length() - 100
 			input := junk.Find("input").Length()
 
 			embedCount := 0
 			junk.Find("embed").Each(func(i int, embed *goquery.Selection) {
-				if videoLinkPattern.MatchString(embed.AttrOr("src", "")) {
+				if _, ok := read.matchEmbed(embed.AttrOr("src", "")); ok {
 					embedCount++
 				}
 			})
 
-			linkDensity := getLinkDensity(junk)
-			contentLength := len(junk.Text())
+			linkDensity := read.linkDensity(junk)
+			contentLength := read.textLengthOf(junk)
 			if (img > 1 && float64(p/img) < 0.5 && !hasAncestorTag(junk, "figure", 0, nil)) ||
 				(!isList && li > p) ||
 				(input > int(math.Floor(float64(p)/3))) ||
-				(!isList && contentLength < 25 && (img == 0 || img > 2) && !hasAncestorTag(junk, "figure", 0, nil)) ||
+				(!isList && contentLength < 25 && ((img == 0 && figureImg == 0) || img > 2) && !hasAncestorTag(junk, "figure", 0, nil)) ||
 				(!isList && read.scoreList[junk.Get(0)] < 25 && linkDensity > 0.2) ||
 				(read.scoreList[junk.Get(0)] >= 25 && linkDensity > 0.5) ||
 				((embedCount == 1 && contentLength < 75) || embedCount > 1) {
@@ -959,68 +1176,64 @@ func hasAncestorTag(s *goquery.Selection, tag string, max int, call func(s *goqu
 // 如 https://dxr.mozilla.org/mozilla-central/rev/71224049c0b52ab190564d3ea0eab089a159a4cf/accessible/html/HTMLTableAccessible.cpp#920
 func (read *Readability) markDataTables(s *goquery.Selection) {
 	s.Find("table").Each(func(i int, table *goquery.Selection) {
-		if table.AttrOr("role", "") == "presentation" {
-			read.readabilityDataTable[table.Get(0)] = false
-			return
-		}
-		if table.AttrOr("datatable", "") == "0" {
-			read.readabilityDataTable[table.Get(0)] = false
-			return
-		}
-		if _, has := table.Attr("summary"); has {
-			read.readabilityDataTable[table.Get(0)] = true
-			return
-		}
-		if table.Find("caption").First().Children().Length() > 0 {
-			read.readabilityDataTable[table.Get(0)] = true
-			return
-		}
-		// 如果表中有任何这些标签的后代，请考虑数据表：
-		var dataTableDescendants = []string{"col", "colgroup", "tfoot", "thead", "th"}
-		for _, tag := range dataTableDescendants {
-			if table.Find(tag).Length() > 0 {
-				read.l("Data table because found data-y descendant")
-				read.readabilityDataTable[table.Get(0)] = true
-				return
-			}
-		}
-		// 嵌套表格表示布局表格：
-		if table.Find("table").Length() > 0 {
-			read.readabilityDataTable[table.Get(0)] = true
-			return
-		}
-		var rows, cols int = getRowAndColumnCount(table)
-		if rows >= 10 || cols > 4 {
-			read.readabilityDataTable[table.Get(0)] = true
-			return
-		}
-		// 现在完全按照尺寸进行：
-		read.readabilityDataTable[table.Get(0)] = rows*cols > 10
+		read.readabilityDataTable[table.Get(0)] = read.isDataTable(table)
 	})
 }
 
-// 获取table的行列数
+// 判断表格是数据表格还是布局表格
+func (read *Readability) isDataTable(table *goquery.Selection) bool {
+	if table.AttrOr("role", "") == "presentation" {
+		return false
+	}
+	if table.AttrOr("datatable", "") == "0" {
+		return false
+	}
+	if _, has := table.Attr("summary"); has {
+		return true
+	}
+	if caption := table.Find("caption").First(); caption.Length() > 0 && caption.Get(0).FirstChild != nil {
+		return true
+	}
+	// 如果表中有任何这些标签的后代，请考虑数据表（嵌套表格中的不算）：
+	var dataTableDescendants = []string{"col", "colgroup", "tfoot", "thead", "th"}
+	for _, tag := range dataTableDescendants {
+		own := table.Find(tag).FilterFunction(func(i int, s *goquery.Selection) bool {
+			return s.Closest("table").Get(0) == table.Get(0)
+		})
+		if own.Length() > 0 {
+			read.l("Data table because found data-y descendant")
+			return true
+		}
+	}
+	// 嵌套表格表示布局表格：
+	if table.Find("table").Length() > 0 {
+		return false
+	}
+	var rows, cols int = getRowAndColumnCount(table)
+	if rows >= 10 || cols > 4 {
+		return true
+	}
+	// 现在完全按照尺寸进行：
+	return rows*cols > 10
+}
+
+// 获取table的行列数：行数为表格自身的行数，列数为各行单元格 colspan 之和的最大值。
+// 与 Mozilla Readability 相同，只按属性计算而不展开合并单元格，耗时与单元格个数成正比
 func getRowAndColumnCount(table *goquery.Selection) (int, int) {
-	rows := 0
+	rows := tableRows(table.Get(0))
 	cols := 0
-	table.Find("tr").Each(func(i int, tr *goquery.Selection) {
-		rowSpan, _ := strconv.Atoi(tr.AttrOr("rowspan", "0"))
-		if rowSpan == 0 {
-			rowSpan = 1
-		}
-		rows += rowSpan
-		// 现在查找与列相关的信息
+	for _, row := range rows {
 		colsInRow := 0
-		tr.Find("td").Each(func(i int, td *goquery.Selection) {
-			colSpan, _ := strconv.Atoi(tr.AttrOr("colspan", "0"))
-			if colSpan == 0 {
-				colSpan = 1
+		for cell := row.node.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+				colsInRow += cellSpan(cell, "colspan", maxColSpan)
 			}
-			colsInRow += colSpan
-		})
-		cols = int(math.Max(float64(colsInRow), 10.0))
-	})
-	return rows, cols
+		}
+		if colsInRow > cols {
+			cols = colsInRow
+		}
+	}
+	return len(rows), cols
 }
 
 // 删除节点及子节点样式属性。
@@ -1045,48 +1258,21 @@ func cleanStyles(s *goquery.Selection) {
 
 // 获取连接密度
 func getLinkDensity(s *goquery.Selection) float64 {
-	textLength := len(s.Text())
-	if textLength == 0 {
+	textLen := textLength(s.Text())
+	if textLen == 0 {
 		return 0
 	}
 	linkLength := 0.0
 	s.Find("a").Each(func(i int, is *goquery.Selection) {
-		linkLength += float64(len(is.Text()))
+		linkLength += float64(textLength(is.Text()))
 	})
-	return linkLength / float64(textLength)
+	return linkLength / float64(textLen)
 }
 
 // 初始化节点分数
 func (read *Readability) initializeScoreSelection(s *goquery.Selection) {
-	switch s.Get(0).Data {
-	case "div":
-		read.scoreList[s.Get(0)] += 5
-		break
-	case "pre":
-	case "td":
-	case "blockquote":
-		read.scoreList[s.Get(0)] += 3
-		break
-	case "address":
-	case "ol":
-	case "ul":
-	case "dl":
-	case "dd":
-	case "dt":
-	case "li":
-	case "form":
-		read.scoreList[s.Get(0)] -= 3
-		break
-	case "h1":
-	case "h2":
-	case "h3":
-	case "h4":
-	case "h5":
-	case "h6":
-	case "th":
-		read.scoreList[s.Get(0)] -= 5
-		break
-	}
+	read.scoreList[s.Get(0)] += read.option.TagWeights[s.Get(0).Data]
+
 	// 获取元素类/标识权重。 使用正则表达式来判断这个元素是好还是坏。
 	read.getClassWeight(s)
 
@@ -1098,7 +1284,7 @@ func (read *Readability) initializeScoreSelection(s *goquery.Selection) {
 
 // 获取元素类/标识权重。 使用正则表达式来判断这个元素是好还是坏。
 func (read *Readability) getClassWeight(s *goquery.Selection) {
-	if !flagIsActive(flagWeightClasses) {
+	if !read.flagIsActive(flagWeightClasses) {
 		return
 	}
 	// 寻找一个特殊的类名
@@ -1138,21 +1324,31 @@ func getSelectionAncestors(s *goquery.Selection, i int) []*goquery.Selection {
 	return ancestors
 }
 
+// 向上查找候选节点时是否已到达 body，节点已脱离文档时同样视为到达
+func isBodyOrDetached(s *goquery.Selection) bool {
+	return s.Length() == 0 || s.Get(0).Data == "body"
+}
+
 // 节点是否含有块级元素
 func hasChildBlockElement(s *goquery.Selection) bool {
-	flag := false
-	s.Children().EachWithBreak(func(i int, is *goquery.Selection) bool {
-		if _, has := divToPElement[is.Get(0).Data]; has {
-			flag = true
-			return false
+	for _, n := range s.Nodes {
+		if hasBlockDescendant(n) {
+			return true
 		}
-		if hasChildBlockElement(is) {
-			flag = true
-			return false
+	}
+	return false
+}
+
+func hasBlockDescendant(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
 		}
-		return true
-	})
-	return flag
+		if _, has := divToPElement[c.Data]; has || hasBlockDescendant(c) {
+			return true
+		}
+	}
+	return false
 }
 
 // 检查此节点是否只有空白，并且具有给定标记的单个元素如果DIV节点包含非空文本节点，
@@ -1163,14 +1359,26 @@ func hasSingleTagInsideElement(s *goquery.Selection, tag string) bool {
 		return false
 	}
 	// 并且不应该有真实内容的文本节点
-	return len(ts(s.Text())) == 0
+	for n := s.Get(0).FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == html.TextNode && len(ts(n.Data)) > 0 {
+			return false
+		}
+	}
+	return true
 }
 
 // 确定节点是否符合短语内容。
 func (read *Readability) isPhrasingContent(n *html.Node) bool {
-	if n != nil && n.Type == html.TextNode || inSlice(phrasingElements, n.Data) {
+	if n == nil {
+		return false
+	}
+	if n.Type == html.TextNode || n.Type == html.ElementNode && inSlice(phrasingElements, n.Data) {
 		return true
 	}
+	// a、del、ins 只有在其子节点均为短语内容时才算短语内容
+	if n.Type != html.ElementNode || (n.Data != "a" && n.Data != "del" && n.Data != "ins") {
+		return false
+	}
 	innerN := n.FirstChild
 	for innerN != nil {
 		if !read.isPhrasingContent(innerN) {
@@ -1235,6 +1443,7 @@ func (read *Readability) checkByline(s *goquery.Selection, matchString string) b
 	innerText := s.Text()
 	if (s.AttrOr("rel", "") == "author" || bylinePattern.MatchString(matchString)) && isValidByline(innerText) {
 		read.article.Byline = ts(innerText)
+		read.article.Authors = splitByline(innerText, read.authorLinks(s))
 		return true
 	}
 	return false
@@ -1242,18 +1451,18 @@ func (read *Readability) checkByline(s *goquery.Selection, matchString string) b
 
 // 合理的作者信息行
 func isValidByline(line string) bool {
-	length := utf8.RuneCountInString(ts(line))
+	length := textLength(line)
 	return length > 0 && length < 100
 }
 
 // 是否启用
-func flagIsActive(flag int) bool {
-	return flags[flag]
+func (read *Readability) flagIsActive(flag int) bool {
+	return read.flags[flag]
 }
 
 // 禁用flag
-func removeFlag(flag int) {
-	flags[flag] = false
+func (read *Readability) removeFlag(flag int) {
+	read.flags[flag] = false
 }
 
 // 从 metadata 获取文章的摘要和作者信息
@@ -1261,9 +1470,6 @@ func (read *Readability) getArticleMetadata() metadata {
 	var md metadata
 	values := make(map[string]string)
 
-	propertyPattern := regexp.MustCompile(`\s*(dc|dcterm|og|twitter)\s*:\s*(author|creator|description|title)\s*`)
-	namePattern := regexp.MustCompile(`^\s*(?:(dc|dcterm|og|twitter|weibo:(article|webpage))\s*[\.:]\s*)?(author|creator|description|title)\s*$`)
-
 	// 提取元数据
 	read.dom.Find("meta").Each(func(i int, s *goquery.Selection) {
 		elementName, hasElName := s.Attr("name")
@@ -1273,20 +1479,18 @@ func (read *Readability) getArticleMetadata() metadata {
 		var matches []string
 
 		if has {
-			if matches = propertyPattern.FindAllString(elementProperty, 0); len(matches) > 0 {
+			if matches = metaPropertyPattern.FindAllString(elementProperty, 0); len(matches) > 0 {
 				for index := len(matches) - 1; index >= 0; index-- {
-					replacer, _ := regexp.Compile(`\s`)
-					name = string(replacer.ReplaceAll([]byte(matches[index]), []byte("")))
+					name = strings.Join(strings.Fields(matches[index]), "")
 					values[name] = ts(content)
 				}
 			}
 		}
 
-		if len(matches) == 0 && hasElName && namePattern.MatchString(elementName) {
+		if len(matches) == 0 && hasElName && metaNamePattern.MatchString(elementName) {
 			name = elementName
 			if hasContent {
-				replacer, _ := regexp.Compile(`\.`)
-				name = string(replacer.ReplaceAll([]byte(normalizeSpace(strings.ToLower(name))), []byte(":")))
+				name = strings.ReplaceAll(normalizeSpace(strings.ToLower(name)), ".", ":")
 				values[name] = ts(content)
 			}
 		}
@@ -1349,11 +1553,6 @@ func (read *Readability) getArticleMetadata() metadata {
 	return md
 }
 
-// 将多个空格替换成单个空格
-func normalizeSpace(str string) string {
-	return whitespacePattern.ReplaceAllString(str, " ")
-}
-
 // 获取文章标题
 func (read *Readability) getArticleTitle() string {
 	var title, originTitle string
@@ -1422,33 +1621,52 @@ func (read *Readability) getArticleTitle() string {
 
 // 预处理HTML文档以提高可读性。 这包括剥离JavaScript，CSS和处理没用的标记等内容。
 func (read *Readability) prepDocument() {
+	// Gist 等以脚本嵌入的内容替换为 iframe
+	read.replaceScriptEmbeds()
+
+	// 公式的 TeX 源码位于 script 中，需在删除脚本之前整理
+	read.replaceMath()
+
 	// 移除所有script标签
 	read.removeTags("script,noscript")
 
 	// 移除所有style标签
 	read.removeTags("style")
 
+	// 整理推文、微博等社交网站的嵌入
+	read.replaceSocialEmbeds()
+
+	// 整理高亮的代码块，代码中的 <br> 是换行
+	read.normalizeCodeBlocks()
+
 	// 将多个连续的<br>替换成<p>
 	read.replaceBrs()
 
 	// 将所有的font替换成span
 	read.replaceSelectionTags(read.dom.Find("font"), "span")
 
+	// 将图片与说明整理为 figure
+	read.normalizeFigures()
+
+	// 将布局表格展开为 div
+	read.flattenLayoutTables()
+
+	// 取出脚注，提取正文后再追加
+	read.extractFootnotes()
+
 }
 
-// 清除所有注释节点
-func removeCommentsAndUnusedAttr(pNode *html.Node) {
+// 清除所有注释节点，只遍历 root 及其子孙节点，class 只保留 preserve 中的与由本库添加的
+func removeCommentsAndUnusedAttr(root *html.Node, preserve []string) {
+	pNode := root
 	for pNode != nil {
-		tmp := pNode
-
 		// 移除所有注释
 		if pNode.Type == html.CommentNode {
-			tmp = pNode.NextSibling
-			if tmp == nil {
-				tmp = pNode.Parent.NextSibling
+			next := nextNodeWithin(pNode, root, false)
+			if pNode.Parent != nil {
+				pNode.Parent.RemoveChild(pNode)
 			}
-			pNode.Parent.RemoveChild(pNode)
-			pNode = tmp
+			pNode = next
 			continue
 		}
 
@@ -1457,6 +1675,17 @@ func removeCommentsAndUnusedAttr(pNode *html.Node) {
 		if pNode.Type == html.ElementNode {
 			for i, attr := range pNode.Attr {
 				j := i - deleted
+				if attr.Key == "class" {
+					// 只保留需要保留的与标记嵌入内容等由本库添加的 class
+					if tagged := taggedClasses(attr.Val, preserve); len(tagged) > 0 {
+						pNode.Attr[j].Val = tagged
+						continue
+					}
+				}
+				// MathML 的属性决定公式的排版
+				if pNode.Namespace == "math" && mathMLAttributes[attr.Key] {
+					continue
+				}
 				if _, has := map[string]struct{}{"id": {}, "src": {}, "href": {},
 					"title": {}, "alt": {}, "target": {}}[attr.Key]; !has {
 					pNode.Attr = pNode.Attr[:j+copy(pNode.Attr[j:], pNode.Attr[j+1:])]
@@ -1464,24 +1693,32 @@ func removeCommentsAndUnusedAttr(pNode *html.Node) {
 				}
 			}
 		}
+		pNode = nextNodeWithin(pNode, root, true)
+	}
+}
 
-		pNode = tmp.FirstChild
-		if pNode != nil {
-			continue
+// class 中需要保留的与由本库添加的部分
+func taggedClasses(class string, preserve []string) string {
+	var tagged []string
+	for _, cls := range strings.Fields(class) {
+		if inSlice(preserve, cls) || taggedClassPattern.MatchString(cls) {
+			tagged = append(tagged, cls)
 		}
-		pNode = tmp.NextSibling
-		if pNode != nil {
-			continue
-		}
-		tmp = tmp.Parent
-		for tmp != nil {
-			pNode = tmp.NextSibling
-			if pNode != nil {
-				break
-			}
-			tmp = tmp.Parent
+	}
+	return strings.Join(tagged, " ")
+}
+
+// 深度优先遍历中 n 的下一个节点，不超出 root 的范围
+func nextNodeWithin(n, root *html.Node, children bool) *html.Node {
+	if children && n.FirstChild != nil {
+		return n.FirstChild
+	}
+	for ; n != nil && n != root; n = n.Parent {
+		if n.NextSibling != nil {
+			return n.NextSibling
 		}
 	}
+	return nil
 }
 
 // 将多个连续的<br>替换成<p>
@@ -1509,9 +1746,9 @@ func (read *Readability) replaceBrs() {
 			next = pNode.NextSibling
 			for next != nil {
 				// 如果我们遇到了其他的 <br><br> 结束添加
-				if pNode.Data == "br" {
-					innerNext := nextElement(next)
-					if innerNext.Data == "br" {
+				if next.Data == "br" {
+					innerNext := nextElement(next.NextSibling)
+					if innerNext != nil && innerNext.Data == "br" {
 						break
 					}
 				}
@@ -1530,7 +1767,7 @@ func (read *Readability) replaceBrs() {
 				pNode.RemoveChild(pNode.LastChild)
 			}
 
-			if pNode.Parent.Data == "p" {
+			if pNode.Parent != nil && pNode.Parent.Data == "p" {
 				pNode.Parent.Data = "div"
 			}
 		}
@@ -1589,7 +1826,7 @@ func (read *Readability) isWhitespace(node *html.Node) bool {
 }
 
 func (read *Readability) isProbablyVisible(sel *goquery.Selection) bool {
-	m, _ := regexp.MatchString(`display:\s*none`, sel.AttrOr("style", ""))
+	m := hiddenStylePattern.MatchString(sel.AttrOr("style", ""))
 	_, m1 := sel.Attr("hidden")
 	return !m && !m1
 }
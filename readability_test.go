@@ -6,33 +6,243 @@
 package readability
 
 import (
-	"io/ioutil"
-	"net/http"
+	"strings"
 	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
 )
 
-func TestParse(t *testing.T) {
-	pageUrls := []string{
-		"http://news.youth.cn/sz/201810/t20181016_11755617.htm",
-		"http://politics.people.com.cn/n1/2018/0612/c1001-30051069.html",
-		"https://www.jianshu.com/p/725c7dc55d58",
+func TestInitializeScoreSelection(t *testing.T) {
+	expected := map[string]float64{
+		"div":        5,
+		"pre":        3,
+		"td":         3,
+		"blockquote": 3,
+		"address":    -3,
+		"ol":         -3,
+		"ul":         -3,
+		"dl":         -3,
+		"dd":         -3,
+		"dt":         -3,
+		"li":         -3,
+		"form":       -3,
+		"h1":         -5,
+		"h2":         -5,
+		"h3":         -5,
+		"h4":         -5,
+		"h5":         -5,
+		"h6":         -5,
+		"th":         -5,
+		"p":          -0.00001,
+		"section":    -0.00001,
+	}
+	read := New(Option{})
+	for tag, score := range expected {
+		s := newTestSelection(tag)
+		read.initializeScoreSelection(s)
+		if got := read.scoreList[s.Get(0)]; got != score {
+			t.Errorf("%s: 初始分 %v，期望 %v", tag, got, score)
+		}
+	}
+}
+
+func TestInitializeScoreSelectionCustomWeights(t *testing.T) {
+	read := New(Option{TagWeights: map[string]float64{"section": 4, "div": 1}})
+	for tag, score := range map[string]float64{"section": 4, "div": 1, "td": 3} {
+		s := newTestSelection(tag)
+		read.initializeScoreSelection(s)
+		if got := read.scoreList[s.Get(0)]; got != score {
+			t.Errorf("%s: 初始分 %v，期望 %v", tag, got, score)
+		}
+	}
+}
+
+func TestRemoveCommentsAndUnusedAttr(t *testing.T) {
+	// 脱离文档的注释节点
+	removeCommentsAndUnusedAttr(&html.Node{Type: html.CommentNode, Data: "注释"}, nil)
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+		`<div id="a" class="x"><!-- 1 --><p style="y">正文<!-- 2 --></p></div><p class="outside">外部</p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	removeCommentsAndUnusedAttr(doc.Find("#a").Get(0), nil)
+	h, _ := doc.Find("body").Html()
+	if expected := `<div id="a"><p>正文</p></div><p class="outside">外部</p>`; h != expected {
+		t.Errorf("得到 %s，期望 %s", h, expected)
+	}
+}
+
+func TestClassesToPreserve(t *testing.T) {
+	paragraph := `<p class="keepme other">这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留指定的 class。</p>`
+	page := `<html><body><article>` + strings.Repeat(paragraph, 5) + `</article></body></html>`
+	article, err := New(Option{ClassesToPreserve: []string{"keepme"}}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
 	}
-	for page := 0; page < len(pageUrls); page++ {
-		resp, err := http.Get(pageUrls[page])
+	if !strings.Contains(article.Content, `<p class="keepme">`) {
+		t.Errorf("正文中没有保留的 class：\n%s", article.Content)
+	}
+	if strings.Contains(article.Content, "other") {
+		t.Errorf("未列出的 class 应被删除：\n%s", article.Content)
+	}
+}
+
+func TestReplaceBrs(t *testing.T) {
+	cases := map[string]string{
+		"<div>甲<br><br>乙<br>丙<br><br>丁</div>": "<div>甲<p>乙<br/>丙</p><p>丁</p></div>",
+		"<div>甲<br><br></div>":                "<div>甲<p></p></div>",
+		"<p>甲<br><br>乙</p>":                   "<div>甲<p>乙</p></div>",
+	}
+	for source, expected := range cases {
+		read := New(Option{})
+		var err error
+		read.dom, err = goquery.NewDocumentFromReader(strings.NewReader(source))
 		if err != nil {
-			continue
+			t.Fatal(err)
+		}
+		read.replaceBrs()
+		if h, _ := read.dom.Find("body").Html(); h != expected {
+			t.Errorf("%s: 得到 %s，期望 %s", source, h, expected)
 		}
-		body, err := ioutil.ReadAll(resp.Body)
+	}
+}
+
+func TestAppendSiblingKeepsTag(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，并且含有几个逗号。</p>`
+	page := `<html><head><meta charset="utf-8"></head><body><article>` + strings.Repeat(paragraph, 5) + `</article><p>A short closing note.</p></body></html>`
+	article, err := New(Option{PageURL: "http://example.com/a.html"}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// p 等标签的兄弟节点保持原样，其余才改为 div
+	if !strings.Contains(article.Content, "<p>A short closing note.</p>") {
+		t.Errorf("兄弟段落未保留为 p：%s", article.Content)
+	}
+}
+
+func TestIsPhrasingContent(t *testing.T) {
+	cases := map[string]bool{
+		`<span>文字</span>`:           true,
+		`<a href="#">链接</a>`:        true,
+		`<a href="#"><b>链接</b></a>`: true,
+		`<a href="#"><p>段落</p></a>`: false,
+		`<ins><div>块</div></ins>`:   false,
+		`<p>段落</p>`:                 false,
+		`<div>块</div>`:              false,
+		`<li>列表</li>`:               false,
+	}
+	read := New(Option{})
+	for h, want := range cases {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<div>` + h + `</div>`))
 		if err != nil {
-			continue
+			t.Fatal(err)
+		}
+		if got := read.isPhrasingContent(doc.Find("body > div").Get(0).FirstChild); got != want {
+			t.Errorf("%s: 得到 %v，期望 %v", h, got, want)
 		}
-		article, err := New(Option{Debug: false, PageURL: pageUrls[page]}).Parse(string(body))
+	}
+}
+
+func TestParagraphizeDivPhrasingContent(t *testing.T) {
+	text := `这是一段足够长的正文文字，用来让候选节点获得分数，并且含有几个逗号。`
+	page := `<html><body><div id="main">开头文字，` + text + `<b>加粗</b>` + text +
+		`<div><p>` + text + text + `</p><p>` + text + text + `</p></div>结尾文字，` + text + `</div></body></html>`
+	article, err := New(Option{PageURL: "http://example.com/a.html"}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 短语内容包进 p 后仍在原来的位置
+	start := strings.Index(article.Content, "<p>开头文字，")
+	block := strings.Index(article.Content, "<div><p>"+text)
+	end := strings.Index(article.Content, "<p>结尾文字，")
+	if start < 0 || block < 0 || end < 0 || !(start < block && block < end) {
+		t.Errorf("短语内容未按原位置分段：%s", article.Content)
+	}
+}
+
+func TestHasSingleTagInsideElement(t *testing.T) {
+	cases := map[string]bool{
+		`<div><p>段落</p></div>`:          true,
+		`<div> <p>段落</p> </div>`:        true,
+		`<div>前言<p>段落</p></div>`:        false,
+		`<div><p>段落</p><p>段落</p></div>`: false,
+		`<div><span>段落</span></div>`:    false,
+	}
+	for h, want := range cases {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(h))
 		if err != nil {
-			panic(err)
-		} else {
-			t.Log("标题", article.Title)
-			t.Log("正文", article.Content)
+			t.Fatal(err)
 		}
-		resp.Body.Close()
+		if got := hasSingleTagInsideElement(doc.Find("div").First(), "p"); got != want {
+			t.Errorf("%s: 得到 %v，期望 %v", h, got, want)
+		}
+	}
+}
+
+func TestScoreUnwrappedSingleParagraph(t *testing.T) {
+	paragraph := `<div><p>这是一段足够长的正文文字，用来让候选节点获得分数，并且含有几个逗号。</p></div>`
+	page := `<html><body><div id="main">` + strings.Repeat(paragraph, 5) + `</div><div>首页 导航菜单</div></body></html>`
+	article, err := New(Option{PageURL: "http://example.com/a.html"}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
 	}
+	// 去掉外层 div 后的 p 参与评分，正文应为 #main 而不是整个 body
+	if strings.Contains(article.Content, "导航菜单") {
+		t.Errorf("正文包含了 #main 以外的内容：%s", article.Content)
+	}
+}
+
+func TestRetryFromOriginalDocument(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，并且含有几个逗号。</p>`
+	page := `<html><body><div class="sidebar">` + strings.Repeat(paragraph, 20) + `</div></body></html>`
+	article, err := New(Option{PageURL: "http://example.com/a.html"}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第一次尝试删掉了 .sidebar，重试时应从原始文档重新开始
+	if strings.Count(article.Content, paragraph) != 20 {
+		t.Errorf("重试未找回被删除的内容：%s", article.Content)
+	}
+}
+
+func TestUseLongestAttempt(t *testing.T) {
+	long := `<div id="main"><div><p>` + strings.Repeat("这是一段很长但没有逗号的正文文字。", 100) + `</p></div></div>`
+	comments := `<div><div class="comment">` + strings.Repeat(`<p>短评，一，二，三，四，五，六，七，八，九，十，十一，十二。</p>`, 10) + `</div></div>`
+	page := `<html><body>` + long + comments + `</body></html>`
+	article, err := New(Option{PageURL: "http://example.com/a.html", CharThreshold: 10000}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 所有尝试都不够长时，返回文字最多的一次，即删掉评论后的第一次
+	if !strings.Contains(article.Content, "没有逗号") || strings.Contains(article.Content, "短评") {
+		t.Errorf("未返回文字最多的结果：%s", article.Content)
+	}
+}
+
+func TestTitleTrimmed(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，并且含有几个逗号。</p>`
+	page := `<html><head><title>如何从网页中提取文章的正文 - 博客</title></head><body><article>` + strings.Repeat(paragraph, 5) + `</article></body></html>`
+	article, err := New(Option{PageURL: "http://example.com/a.html"}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 去掉网站名后不应留下末尾的空格
+	if article.Title != "如何从网页中提取文章的正文" {
+		t.Errorf("标题 %q，期望 %q", article.Title, "如何从网页中提取文章的正文")
+	}
+}
+
+func newTestSelection(tag string) *goquery.Selection {
+	n := &html.Node{Type: html.ElementNode, Data: tag}
+	root := &html.Node{Type: html.ElementNode, Data: "body"}
+	root.AppendChild(n)
+	return goquery.NewDocumentFromNode(root).FindNodes(n)
+}
+
+func newTestSelectionWithText(tag, text string) *goquery.Selection {
+	s := newTestSelection(tag)
+	s.Get(0).AppendChild(&html.Node{Type: html.TextNode, Data: text})
+	return s
 }
@@ -0,0 +1,273 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 净化：按标签、属性与 URL 协议白名单过滤正文，保证输出可以直接嵌入页面而不会执行脚本。
+// 不在白名单中的标签去掉标签保留内容，sanitizeDropTags 中的标签连同内容一起删除，
+// 事件属性（on*）与 style 总是删除，URL 属性的协议不在白名单中时删除该属性。
+
+var (
+	// 含有脚本、样式或表单等不应作为文字保留的内容，不在白名单中时连同内容一起删除
+	sanitizeDropTags = map[string]bool{
+		"applet": true, "audio": true, "base": true, "button": true, "embed": true,
+		"form": true, "frame": true, "frameset": true, "head": true, "iframe": true,
+		"input": true, "link": true, "math": true, "meta": true, "noembed": true,
+		"noframes": true, "noscript": true, "object": true, "option": true, "param": true,
+		"script": true, "select": true, "style": true, "svg": true, "template": true,
+		"textarea": true, "title": true, "video": true, "xmp": true,
+	}
+	// 值为 URL 的属性
+	sanitizeURLAttrs = map[string]bool{
+		"action": true, "background": true, "cite": true, "data": true, "formaction": true,
+		"href": true, "longdesc": true, "poster": true, "src": true, "xlink:href": true,
+	}
+	// 可以作为 img src 的 data URI，不含可执行脚本的 SVG
+	dataImagePattern = regexp.MustCompile(`(?i)^data:image/(png|jpe?g|gif|webp|avif|bmp);base64,[a-z0-9+/=\s]*$`)
+)
+
+// SanitizePolicy 净化白名单，零值会删除所有标签，通常基于 DefaultSanitizePolicy 修改
+type SanitizePolicy struct {
+	// 允许的标签及各自允许的属性，"*" 中的属性对所有允许的标签生效
+	Tags map[string][]string
+	// 允许的 URL 协议，不含冒号，相对地址总是允许
+	Schemes []string
+	// 是否允许 img 使用 data:image/... 的 base64 图片
+	AllowDataImages bool
+	// 是否为 target 属性的链接加上 rel="noopener noreferrer"
+	NoOpener bool
+	// 是否保留 MathML 公式，只保留排版用的元素与属性
+	MathML bool
+	// 不为空时作为所有 iframe 的 sandbox 属性，替换原有的值
+	IframeSandbox string
+}
+
+// DefaultSanitizePolicy 默认白名单：常见的文本、列表、表格、图片、音视频、MathML 公式与 http(s) 的 iframe，
+// 不允许 style、表单、svg 与 object/embed。iframe 加上 sandbox，只允许播放器所需的脚本与弹出窗口，
+// 不能跳转顶层页面或提交表单；id 只用于脚注与标题的锚点，以免覆盖页面中的元素
+func DefaultSanitizePolicy() *SanitizePolicy {
+	p := &SanitizePolicy{
+		Tags: map[string][]string{
+			"*":          {"class", "title", "lang", "dir"},
+			"a":          {"href", "name", "target", "rel"},
+			"img":        {"src", "srcset", "sizes", "alt", "width", "height", "loading"},
+			"iframe":     {"src", "width", "height", "allowfullscreen", "frameborder"},
+			"video":      {"src", "poster", "controls", "width", "height", "preload", "loop", "muted"},
+			"audio":      {"src", "controls", "preload", "loop"},
+			"picture":    nil,
+			"source":     {"src", "srcset", "sizes", "type", "media"},
+			"blockquote": {"cite"},
+			"q":          {"cite"},
+			"del":        {"cite", "datetime"},
+			"ins":        {"cite", "datetime"},
+			"time":       {"datetime"},
+			"ol":         {"start", "reversed", "type"},
+			"li":         {"value", "id"},
+			"sup":        {"id"},
+			"table":      {"summary"},
+			"col":        {"span"},
+			"colgroup":   {"span"},
+			"td":         {"colspan", "rowspan", "headers"},
+			"th":         {"colspan", "rowspan", "headers", "scope", "abbr"},
+			"details":    {"open"},
+		},
+		Schemes:         []string{"http", "https", "mailto"},
+		AllowDataImages: true,
+		NoOpener:        true,
+		MathML:          true,
+		IframeSandbox:   "allow-scripts allow-same-origin allow-presentation allow-popups",
+	}
+	for _, tag := range []string{"h1", "h2", "h3", "h4", "h5", "h6"} {
+		p.Tags[tag] = []string{"id"}
+	}
+	for _, tag := range []string{"abbr", "address", "article", "aside", "b", "bdi", "bdo",
+		"br", "caption", "cite", "code", "dd", "dfn", "div", "dl", "dt", "em", "figcaption",
+		"figure", "footer", "header", "hr", "i", "kbd",
+		"main", "mark", "p", "pre", "rp", "rt", "ruby", "s", "samp", "section", "small",
+		"span", "strike", "strong", "sub", "summary", "tbody", "tfoot", "thead", "tr",
+		"u", "ul", "var", "wbr"} {
+		p.Tags[tag] = nil
+	}
+	return p
+}
+
+// Sanitize 净化 HTML 片段
+func (p *SanitizePolicy) Sanitize(s string) (string, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(s), body)
+	if err != nil {
+		return "", err
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	p.sanitizeChildren(body)
+	var b strings.Builder
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&b, c); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// 净化 n 的所有子孙节点，n 本身不做处理
+func (p *SanitizePolicy) sanitizeChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.TextNode:
+		case html.ElementNode:
+			if c.Namespace == "math" && p.MathML && mathMLTags[c.Data] {
+				attrs := c.Attr[:0]
+				for _, a := range c.Attr {
+					if len(a.Namespace) == 0 && mathMLAttributes[a.Key] {
+						attrs = append(attrs, a)
+					}
+				}
+				c.Attr = attrs
+				p.sanitizeChildren(c)
+				break
+			}
+			// svg、math 中的元素与 HTML 同名时含义不同，如 svg 中的 <a xlink:href>
+			foreign := c.Namespace == "svg" || c.Namespace == "math"
+			if _, allowed := p.Tags[c.Data]; allowed && !foreign {
+				p.sanitizeAttrs(c)
+				if c.Data == "iframe" {
+					if !hasAttr(c, "src") {
+						// 没有合法地址的 iframe 没有意义，srcdoc 等也已被删除
+						n.RemoveChild(c)
+						break
+					}
+					// iframe 的内容是原样输出的文本，换一种方式解析时会成为标签
+					for c.FirstChild != nil {
+						c.RemoveChild(c.FirstChild)
+					}
+					if len(p.IframeSandbox) > 0 {
+						setNodeAttr(c, "sandbox", p.IframeSandbox)
+					}
+					break
+				}
+				p.sanitizeChildren(c)
+				break
+			}
+			if sanitizeDropTags[c.Data] || foreign {
+				n.RemoveChild(c)
+				break
+			}
+			// 去掉标签保留内容，内容移出后从第一个子节点继续
+			first := c.FirstChild
+			for c.FirstChild != nil {
+				child := c.FirstChild
+				c.RemoveChild(child)
+				n.InsertBefore(child, c)
+			}
+			n.RemoveChild(c)
+			if first != nil {
+				next = first
+			}
+		default:
+			// 注释、doctype 等
+			n.RemoveChild(c)
+		}
+		c = next
+	}
+}
+
+func (p *SanitizePolicy) sanitizeAttrs(n *html.Node) {
+	attrs := n.Attr[:0]
+	hasTarget := false
+	for _, a := range n.Attr {
+		key := strings.ToLower(a.Key)
+		if len(a.Namespace) > 0 || strings.HasPrefix(key, "on") || key == "style" ||
+			(!inSlice(p.Tags[n.Data], key) && !inSlice(p.Tags["*"], key)) {
+			continue
+		}
+		switch {
+		case sanitizeURLAttrs[key]:
+			if !p.allowURL(n.Data, key, a.Val) {
+				continue
+			}
+		case key == "srcset":
+			if !p.allowSrcset(a.Val) {
+				continue
+			}
+		case key == "target":
+			hasTarget = true
+		}
+		a.Key = key
+		attrs = append(attrs, a)
+	}
+	n.Attr = attrs
+	if hasTarget && p.NoOpener && n.Data == "a" {
+		rel := "noopener noreferrer"
+		for i, a := range n.Attr {
+			if a.Key == "rel" {
+				n.Attr[i].Val = strings.TrimSpace(a.Val + " " + rel)
+				return
+			}
+		}
+		n.Attr = append(n.Attr, html.Attribute{Key: "rel", Val: rel})
+	}
+}
+
+// URL 的协议是否在白名单中。浏览器会忽略地址中的控制字符与空白，
+// 如 "java\tscript:"，因此先去除再判断。
+func (p *SanitizePolicy) allowURL(tag, attr, val string) bool {
+	u := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, val)
+	colon := strings.IndexByte(u, ':')
+	if colon < 0 || strings.ContainsAny(u[:colon], "/?#") {
+		// 相对地址
+		return true
+	}
+	scheme := strings.ToLower(u[:colon])
+	if scheme == "data" {
+		return p.AllowDataImages && tag == "img" && attr == "src" && dataImagePattern.MatchString(val)
+	}
+	return inSlice(p.Schemes, scheme)
+}
+
+// srcset 中的每个地址都需合法
+func (p *SanitizePolicy) allowSrcset(val string) bool {
+	for _, candidate := range strings.Split(val, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 && !p.allowURL("source", "srcset", fields[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+func setNodeAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
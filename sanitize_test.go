@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"strings"
+	"testing"
+)
+
+// 常见的 XSS 写法，净化后均不应含有可执行的内容
+var xssVectors = []string{
+	`<script>alert(1)</script>`,
+	`<SCRIPT SRC=http://xss.example/xss.js></SCRIPT>`,
+	`<img src="javascript:alert(1)">`,
+	`<img src=x onerror=alert(1)>`,
+	`<IMG SRC=JaVaScRiPt:alert(1)>`,
+	`<img src="jav&#x09;ascript:alert(1)">`,
+	`<img src="&#106;&#97;&#118;&#97;&#115;&#99;&#114;&#105;&#112;&#116;&#58;alert(1)">`,
+	`<img src=" &#14;  javascript:alert(1);">`,
+	`<img srcset="x.png 1x, javascript:alert(1) 2x">`,
+	`<img src="data:image/svg+xml;base64,PHN2ZyBvbmxvYWQ9YWxlcnQoMSk+">`,
+	`<a href="javascript:alert(1)">x</a>`,
+	`<a href="  JAVASCRIPT:alert(1)">x</a>`,
+	`<a href="java&#0;script:alert(1)">x</a>`,
+	`<a href="vbscript:msgbox(1)">x</a>`,
+	`<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`,
+	`<a href="#" onclick="alert(1)">x</a>`,
+	`<svg onload=alert(1)><script>alert(1)</script></svg>`,
+	`<svg><a xlink:href="javascript:alert(1)"><text>x</text></a></svg>`,
+	`<math><mi xlink:href="javascript:alert(1)">x</mi></math>`,
+	`<math><annotation-xml encoding="text/html"><img src=x onerror=alert(1)></annotation-xml></math>`,
+	`<math><maction actiontype="statusline" href="javascript:alert(1)"><mglyph src="x"></maction></math>`,
+	`<object data="javascript:alert(1)"></object>`,
+	`<object data="https://www.youtube.com/v/x"><param name="allowScriptAccess" value="always"></object>`,
+	`<embed src="javascript:alert(1)">`,
+	`<iframe src="javascript:alert(1)"></iframe>`,
+	`<iframe srcdoc="<script>alert(1)</script>"></iframe>`,
+	`<iframe src="https://a"><script>alert(1)</script></iframe>`,
+	`<form action="javascript:alert(1)"><button formaction="javascript:alert(1)">x</button></form>`,
+	`<input onfocus=alert(1) autofocus>`,
+	`<body onload=alert(1)>`,
+	`<details open ontoggle=alert(1)>`,
+	`<video poster="javascript:alert(1)"><source src="x" onerror="alert(1)"></video>`,
+	`<div style="background:url(javascript:alert(1))">x</div>`,
+	`<p style="width: expression(alert(1))">x</p>`,
+	`<style>@import 'http://xss.example/x.css';</style>`,
+	`<link rel="stylesheet" href="http://xss.example/x.css">`,
+	`<meta http-equiv="refresh" content="0;url=javascript:alert(1)">`,
+	`<base href="javascript:alert(1)//">`,
+	`<blockquote cite="javascript:alert(1)">x</blockquote>`,
+	`<table background="javascript:alert(1)"><tr><td>x</td></tr></table>`,
+	`<noscript><p title="</noscript><img src=x onerror=alert(1)>">`,
+	`<template><script>alert(1)</script></template>`,
+	`<div><!--<img src=x onerror=alert(1)>--></div>`,
+	`<x-custom onmouseover=alert(1)>x</x-custom>`,
+	`<a href="http://example.com" target="_blank">x</a>`,
+}
+
+func TestSanitizeXSSVectors(t *testing.T) {
+	p := DefaultSanitizePolicy()
+	for _, v := range xssVectors {
+		out, err := p.Sanitize(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lower := strings.ToLower(out)
+		for _, bad := range []string{"<script", "javascript:", "vbscript:", "data:text", "svg+xml", "<svg", "xlink", "<annotation-xml", "<mglyph",
+			"<object", "<embed", "<form", "<button", "<input", "<style", "<link", "<meta", "<base",
+			" on", "style=", "srcdoc", "expression(", "<!--", "<template", "<x-custom"} {
+			if strings.Contains(lower, bad) {
+				t.Errorf("%s\n净化后仍含有 %q：%s", v, bad, out)
+			}
+		}
+		// 保留的 iframe 不能有内容
+		if strings.Contains(lower, "<iframe") && !strings.Contains(lower, `<iframe src="https://a" sandbox="allow-scripts allow-same-origin allow-presentation allow-popups"></iframe>`) {
+			t.Errorf("%s\n净化后仍含有 iframe：%s", v, out)
+		}
+	}
+}
+
+func TestSanitizeKeepsContent(t *testing.T) {
+	p := DefaultSanitizePolicy()
+	cases := []struct{ in, out string }{
+		{`<p class="a" data-x="1">文字<b>加粗</b></p>`, `<p class="a">文字<b>加粗</b></p>`},
+		{`<font color="red">红色</font>`, `红色`},
+		{`<a href="/rel" target="_blank">链接</a>`, `<a href="/rel" target="_blank" rel="noopener noreferrer">链接</a>`},
+		{`<a href="mailto:a@example.com">邮件</a>`, `<a href="mailto:a@example.com">邮件</a>`},
+		{`<img src="data:image/png;base64,iVBORw0KGgo=" alt="图">`, `<img src="data:image/png;base64,iVBORw0KGgo=" alt="图"/>`},
+		{`<img srcset="a.png 1x, https://example.com/b.png 2x">`, `<img srcset="a.png 1x, https://example.com/b.png 2x"/>`},
+		{`<iframe src="https://player.example.com/1" onload="x()" sandbox="allow-top-navigation"></iframe>`,
+			`<iframe src="https://player.example.com/1" sandbox="allow-scripts allow-same-origin allow-presentation allow-popups"></iframe>`},
+		// id 只保留在脚注与标题上
+		{`<div id="login"><h2 id="intro">标题</h2><p>文字<sup id="fnref-1"><a href="#fn-1">1</a></sup></p></div>`,
+			`<div><h2 id="intro">标题</h2><p>文字<sup id="fnref-1"><a href="#fn-1">1</a></sup></p></div>`},
+		{`<table><tr><td colspan="2" bgcolor="red">格</td></tr></table>`, `<table><tbody><tr><td colspan="2">格</td></tr></tbody></table>`},
+		{`<section><custom-tag>自定义<i>标签</i></custom-tag></section>`, `<section>自定义<i>标签</i></section>`},
+		{`<math display="block" onclick="x()"><mi mathvariant="bold" style="color:red">x</mi><annotation encoding="application/x-tex">\mathbf{x}</annotation></math>`,
+			`<math display="block"><mi mathvariant="bold">x</mi><annotation encoding="application/x-tex">\mathbf{x}</annotation></math>`},
+	}
+	for _, c := range cases {
+		out, err := p.Sanitize(c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != c.out {
+			t.Errorf("%s\n得到 %s\n期望 %s", c.in, out, c.out)
+		}
+	}
+}
+
+func TestSanitizePolicyConfig(t *testing.T) {
+	p := DefaultSanitizePolicy()
+	delete(p.Tags, "iframe")
+	p.Tags["img"] = []string{"src"}
+	p.Schemes = []string{"https"}
+	p.AllowDataImages = false
+	out, err := p.Sanitize(`<iframe src="https://example.com/"></iframe><img src="http://example.com/a.png" alt="a"><img src="data:image/png;base64,AAAA"><img src="https://example.com/b.png" alt="b">`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<img/><img/><img src="https://example.com/b.png"/>`; out != want {
+		t.Errorf("得到 %s\n期望 %s", out, want)
+	}
+}
+
+func TestParseSanitize(t *testing.T) {
+	page := `<html><body><article>` + strings.Repeat(`<p>这是一段足够长的正文文字，用来让候选节点获得分数。<a href="data:text/html,x">链接</a><img src="javascript:alert(1)"></p>`, 6) + `</article></body></html>`
+	article, err := New(Option{}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(article.Content, "javascript:") {
+		t.Fatal("未配置净化时应保持原有行为")
+	}
+	article, err = New(Option{Sanitizer: DefaultSanitizePolicy()}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(article.Content, "javascript:") || !strings.Contains(article.Content, "<a>链接</a><img/>") {
+		t.Errorf("正文未被净化：%s", article.Content)
+	}
+}
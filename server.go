@@ -0,0 +1,441 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// 提取服务：
+//
+//	POST /extract          请求体为 HTML（?url= 指定网页地址）或 JSON {url, html, options}
+//	GET  /extract?url=...  获取并解析网页
+//	GET  /healthz          健康检查
+//	GET  /metrics          Prometheus 文本格式的指标
+//
+// 成功时返回 Article 的 JSON，失败时返回 {"error": "..."}。获取网页与分页时默认只连接公网地址，
+// 见 ServerOption.AllowIP。
+
+// ServerOption 提取服务配置
+type ServerOption struct {
+	// 每次提取的基础配置，可被请求中的 options 覆盖部分字段
+	Option Option
+	// 请求体的最大字节数
+	MaxRequestSize int64
+	// 单个请求的超时时间，包括获取网页与提取正文
+	Timeout time.Duration
+	// 是否允许连接该地址，用于 GET /extract 与获取分页。为空时只允许公网地址，
+	// 拒绝回环、内网、链路本地等地址，以免服务被用来访问内部网络。
+	// 自定义的 Fetcher 与 RoundTripper 无法在连接时检查，改为在请求前检查主机名解析出的地址
+	AllowIP func(ip net.IP) bool
+}
+
+// ErrAddressNotAllowed 网页地址解析到了 ServerOption.AllowIP 不允许的地址
+var ErrAddressNotAllowed = errors.New("不允许访问该地址")
+
+// 运营商级 NAT 等 net.IP 没有判断的非公网地址
+var nonPublicNets = []*net.IPNet{
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+	{IP: net.IPv4(192, 0, 0, 0), Mask: net.CIDRMask(24, 32)},
+	{IP: net.IPv4(198, 18, 0, 0), Mask: net.CIDRMask(15, 32)},
+	{IP: net.IPv4(240, 0, 0, 0), Mask: net.CIDRMask(4, 32)},
+}
+
+// Server 提取服务，实现了 http.Handler
+type Server struct {
+	option ServerOption
+	mux    *http.ServeMux
+
+	inFlight int64
+	mu       sync.Mutex
+	requests map[serverMetricKey]uint64
+	duration map[string]*serverDuration
+}
+
+type serverMetricKey struct {
+	path string
+	code int
+}
+
+type serverDuration struct {
+	sum   float64
+	count uint64
+}
+
+// 请求中可覆盖的配置
+type extractOptions struct {
+	CharThreshold     int      `json:"charThreshold"`
+	NbTopCandidates   int      `json:"nbTopCandidates"`
+	MaxNodeNum        int      `json:"maxNodeNum"`
+	MaxPages          int      `json:"maxPages"`
+	ClassesToPreserve []string `json:"classesToPreserve"`
+}
+
+type extractRequest struct {
+	URL     string          `json:"url"`
+	HTML    string          `json:"html"`
+	Options *extractOptions `json:"options"`
+}
+
+// 带状态码的错误
+type serverError struct {
+	code int
+	err  error
+}
+
+func (e *serverError) Error() string {
+	return e.err.Error()
+}
+
+// NewServer 新建提取服务
+func NewServer(o ServerOption) *Server {
+	if o.MaxRequestSize <= 0 {
+		o.MaxRequestSize = defaultMaxBodySize
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = defaultTimeout
+	}
+	if o.AllowIP == nil {
+		o.AllowIP = isPublicIP
+	}
+	// 获取网页与分页都只连接允许的地址
+	o.Option.HTTPClient = restrictClient(o.Option.HTTPClient, o.AllowIP)
+	if f, ok := o.Option.Fetcher.(*HTTPFetcher); ok {
+		restricted := *f
+		restricted.Client = o.Option.HTTPClient
+		if f.Client != nil {
+			restricted.Client = restrictClient(f.Client, o.AllowIP)
+		}
+		o.Option.Fetcher = &restricted
+	}
+	if o.Option.Fetcher != nil {
+		o.Option.Fetcher = &restrictedFetcher{fetcher: o.Option.Fetcher, allow: o.AllowIP}
+	}
+	s := &Server{
+		option:   o,
+		mux:      http.NewServeMux(),
+		requests: make(map[serverMetricKey]uint64),
+		duration: make(map[string]*serverDuration),
+	}
+	s.mux.HandleFunc("/extract", s.handleExtract)
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	return s
+}
+
+// ServeHTTP 处理请求并记录指标
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, pattern := s.mux.Handler(r)
+	if len(pattern) == 0 {
+		pattern = "other"
+	}
+	atomic.AddInt64(&s.inFlight, 1)
+	defer atomic.AddInt64(&s.inFlight, -1)
+	start := time.Now()
+	sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+	s.mux.ServeHTTP(sw, r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[serverMetricKey{pattern, sw.code}]++
+	d := s.duration[pattern]
+	if d == nil {
+		d = new(serverDuration)
+		s.duration[pattern] = d
+	}
+	d.sum += time.Since(start).Seconds()
+	d.count++
+}
+
+func (s *Server) handleExtract(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.option.Timeout)
+	defer cancel()
+
+	var article *Article
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		article, err = s.extractURL(ctx, r.URL.Query().Get("url"))
+	case http.MethodPost:
+		article, err = s.extractBody(ctx, w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		err = &serverError{http.StatusMethodNotAllowed, errors.New("只支持 GET 和 POST")}
+	}
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, article)
+}
+
+func (s *Server) extractURL(ctx context.Context, pageURL string) (*Article, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return nil, &serverError{http.StatusBadRequest, fmt.Errorf("无效的网页地址：%q", pageURL)}
+	}
+	article, err := ParseURL(ctx, pageURL, s.option.Option)
+	switch {
+	case err == nil || ctx.Err() != nil:
+	case errors.Is(err, ErrAddressNotAllowed):
+		err = &serverError{http.StatusForbidden, err}
+	default:
+		// 获取网页失败与提取失败统一视为上游错误
+		err = &serverError{http.StatusBadGateway, err}
+	}
+	return article, err
+}
+
+// 返回只连接 allow 允许的地址的 client。*http.Transport 在建立连接时检查解析后的地址，重定向与
+// DNS 变化也无法绕过；代理会使检查失效，因此不使用代理。其他 RoundTripper 无法在连接时检查，
+// 改为在每次请求（包括重定向）前解析主机名并检查
+func restrictClient(c *http.Client, allow func(ip net.IP) bool) *http.Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t, ok := base.(*http.Transport)
+	if !ok {
+		restricted := *c
+		restricted.Transport = &restrictedTransport{base: base, allow: allow}
+		return &restricted
+	}
+	t = t.Clone()
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allow(ip) {
+				return fmt.Errorf("%w：%s", ErrAddressNotAllowed, host)
+			}
+			return nil
+		},
+	}
+	t.Proxy = nil
+	t.DialContext = dialer.DialContext
+	t.DialTLSContext = nil
+	restricted := *c
+	restricted.Transport = t
+	return &restricted
+}
+
+// 在请求前检查 RoundTripper 要访问的主机
+type restrictedTransport struct {
+	base  http.RoundTripper
+	allow func(ip net.IP) bool
+}
+
+func (t *restrictedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := checkHost(req.Context(), req.URL.Hostname(), t.allow); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// 在获取前检查 Fetcher 要访问的主机。Fetcher 自己如何连接无从得知，所以对所有 Fetcher
+// 都先做这一检查；HTTPFetcher 另外在建立连接时检查
+type restrictedFetcher struct {
+	fetcher Fetcher
+	allow   func(ip net.IP) bool
+}
+
+func (f *restrictedFetcher) Fetch(ctx context.Context, pageURL string) (string, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	if err := checkHost(ctx, u.Hostname(), f.allow); err != nil {
+		return "", err
+	}
+	return f.fetcher.Fetch(ctx, pageURL)
+}
+
+// 解析主机名，任一地址不被允许时返回 ErrAddressNotAllowed
+func checkHost(ctx context.Context, host string, allow func(ip net.IP) bool) error {
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return err
+		}
+		ips = ips[:0]
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	for _, ip := range ips {
+		if !allow(ip) {
+			return fmt.Errorf("%w：%s", ErrAddressNotAllowed, host)
+		}
+	}
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range nonPublicNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) extractBody(ctx context.Context, w http.ResponseWriter, r *http.Request) (*Article, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.option.MaxRequestSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, ErrBodyTooLarge
+		}
+		return nil, &serverError{http.StatusBadRequest, err}
+	}
+	// 请求体为 HTML 时按 Content-Type 中声明的编码转码，JSON 中的 HTML 已是 UTF-8
+	req := extractRequest{URL: r.URL.Query().Get("url"), HTML: string(body)}
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	cs := params["charset"]
+	if mediaType == "application/json" {
+		req, cs = extractRequest{}, ""
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, &serverError{http.StatusBadRequest, err}
+		}
+	}
+	if len(strings.TrimSpace(req.HTML)) == 0 {
+		return nil, &serverError{http.StatusBadRequest, errors.New("HTML 为空")}
+	}
+
+	o := s.option.Option
+	o.PageURL = req.URL
+	if opts := req.Options; opts != nil {
+		if opts.CharThreshold > 0 {
+			o.CharThreshold = opts.CharThreshold
+		}
+		if opts.NbTopCandidates > 0 {
+			o.NbTopCandidates = opts.NbTopCandidates
+		}
+		if opts.MaxNodeNum > 0 {
+			o.MaxNodeNum = opts.MaxNodeNum
+		}
+		if opts.MaxPages > 0 {
+			o.MaxPages = opts.MaxPages
+		}
+		o.ClassesToPreserve = append(append([]string(nil), o.ClassesToPreserve...), opts.ClassesToPreserve...)
+	}
+	read := New(o)
+	read.charset = cs
+	article, err := read.ParseContext(ctx, req.HTML)
+	if err != nil && ctx.Err() == nil {
+		err = &serverError{http.StatusUnprocessableEntity, err}
+	}
+	return article, err
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, "ok\n")
+}
+
+// 以 Prometheus 文本格式输出请求数、耗时与处理中的请求数
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	keys := make([]serverMetricKey, 0, len(s.requests))
+	for k := range s.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].path != keys[j].path {
+			return keys[i].path < keys[j].path
+		}
+		return keys[i].code < keys[j].code
+	})
+	paths := make([]string, 0, len(s.duration))
+	for p := range s.duration {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	b.WriteString("# HELP readability_http_requests_total Total number of HTTP requests.\n")
+	b.WriteString("# TYPE readability_http_requests_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "readability_http_requests_total{path=%q,code=\"%d\"} %d\n", k.path, k.code, s.requests[k])
+	}
+	b.WriteString("# HELP readability_http_request_duration_seconds Time spent handling HTTP requests.\n")
+	b.WriteString("# TYPE readability_http_request_duration_seconds summary\n")
+	for _, p := range paths {
+		d := s.duration[p]
+		fmt.Fprintf(&b, "readability_http_request_duration_seconds_sum{path=%q} %g\n", p, d.sum)
+		fmt.Fprintf(&b, "readability_http_request_duration_seconds_count{path=%q} %d\n", p, d.count)
+	}
+	s.mu.Unlock()
+	b.WriteString("# HELP readability_http_requests_in_flight Number of HTTP requests being handled.\n")
+	b.WriteString("# TYPE readability_http_requests_in_flight gauge\n")
+	fmt.Fprintf(&b, "readability_http_requests_in_flight %d\n", atomic.LoadInt64(&s.inFlight))
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	io.WriteString(w, b.String())
+}
+
+func writeServerError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	var se *serverError
+	switch {
+	case errors.As(err, &se):
+		code = se.code
+	case errors.Is(err, ErrBodyTooLarge):
+		code = http.StatusRequestEntityTooLarge
+	case errors.Is(err, context.DeadlineExceeded):
+		code = http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		// 客户端已断开，沿用 nginx 的 499
+		code = 499
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+// 记录响应状态码
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func serverRequest(t *testing.T, h http.Handler, method, target, contentType, body string) (int, string) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if len(contentType) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	b, err := io.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, string(b)
+}
+
+func TestServerExtractPost(t *testing.T) {
+	server := NewServer(ServerOption{})
+
+	// 原始 HTML，网页地址由 ?url= 指定，编码由 Content-Type 指定
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String(testFetchPage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, body := serverRequest(t, server, http.MethodPost, "/extract?url=http://example.com/news/a.html", "text/html; charset=gbk", gbk)
+	if code != http.StatusOK {
+		t.Fatalf("状态码 %d：%s", code, body)
+	}
+	var article Article
+	if err := json.Unmarshal([]byte(body), &article); err != nil {
+		t.Fatal(err)
+	}
+	if article.Title != "获取网页的测试标题" || !strings.Contains(article.Content, "http://example.com/news/next.html") {
+		t.Errorf("提取结果有误：%+v", article)
+	}
+
+	// JSON 请求
+	req, err := json.Marshal(map[string]interface{}{
+		"url":     "http://example.com/b.html",
+		"html":    strings.Replace(testFetchPage(), `<section class="content">`, `<section class="content keepme">`, 1),
+		"options": map[string]interface{}{"charThreshold": 100, "classesToPreserve": []string{"keepme"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, body = serverRequest(t, server, http.MethodPost, "/extract", "application/json", string(req))
+	if code != http.StatusOK {
+		t.Fatalf("状态码 %d：%s", code, body)
+	}
+	article = Article{}
+	if err := json.Unmarshal([]byte(body), &article); err != nil {
+		t.Fatal(err)
+	}
+	if article.URL != "http://example.com/b.html" || !strings.Contains(article.Content, "http://example.com/next.html") {
+		t.Errorf("提取结果有误：%+v", article)
+	}
+	if !strings.Contains(article.Content, `class="keepme"`) {
+		t.Errorf("请求中的 classesToPreserve 未生效：%s", article.Content)
+	}
+}
+
+func TestServerExtractErrors(t *testing.T) {
+	server := NewServer(ServerOption{MaxRequestSize: 1024})
+	cases := []struct {
+		method, target, contentType, body string
+		code                              int
+	}{
+		{http.MethodPost, "/extract", "text/html", strings.Repeat("a", 2048), http.StatusRequestEntityTooLarge},
+		{http.MethodPost, "/extract", "application/json", "{", http.StatusBadRequest},
+		{http.MethodPost, "/extract", "text/html", "   ", http.StatusBadRequest},
+		{http.MethodPost, "/extract", "text/html", "<html><body></body></html>", http.StatusUnprocessableEntity},
+		{http.MethodGet, "/extract?url=file:///etc/passwd", "", "", http.StatusBadRequest},
+		{http.MethodGet, "/extract", "", "", http.StatusBadRequest},
+		{http.MethodDelete, "/extract", "", "", http.StatusMethodNotAllowed},
+	}
+	for _, c := range cases {
+		code, body := serverRequest(t, server, c.method, c.target, c.contentType, c.body)
+		if code != c.code {
+			t.Errorf("%s %s：状态码 %d，期望 %d", c.method, c.target, code, c.code)
+		}
+		var resp map[string]string
+		if err := json.Unmarshal([]byte(body), &resp); err != nil || len(resp["error"]) == 0 {
+			t.Errorf("%s %s：错误响应 %q", c.method, c.target, body)
+		}
+	}
+}
+
+func TestServerExtractGet(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/news/article.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, testFetchPage())
+	})
+	mux.HandleFunc("/slow.html", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	upstream := httptest.NewServer(mux)
+	defer upstream.Close()
+
+	// 测试服务器在回环地址上
+	allowAll := func(net.IP) bool { return true }
+	server := NewServer(ServerOption{Option: Option{HTTPClient: upstream.Client()}, Timeout: 100 * time.Millisecond, AllowIP: allowAll})
+	code, body := serverRequest(t, server, http.MethodGet, "/extract?url="+upstream.URL+"/news/article.html", "", "")
+	if code != http.StatusOK {
+		t.Fatalf("状态码 %d：%s", code, body)
+	}
+	if !strings.Contains(body, upstream.URL+"/news/next.html") {
+		t.Errorf("相对链接未按网页地址转换：%s", body)
+	}
+	if code, body = serverRequest(t, server, http.MethodGet, "/extract?url="+upstream.URL+"/missing.html", "", ""); code != http.StatusBadGateway {
+		t.Errorf("状态码 %d，期望 502：%s", code, body)
+	}
+	if code, body = serverRequest(t, server, http.MethodGet, "/extract?url="+upstream.URL+"/slow.html", "", ""); code != http.StatusGatewayTimeout {
+		t.Errorf("状态码 %d，期望 504：%s", code, body)
+	}
+}
+
+func TestServerAllowIP(t *testing.T) {
+	var hits int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, testFetchPage())
+	}))
+	defer upstream.Close()
+
+	// 默认不允许访问回环地址，分页也一样
+	server := NewServer(ServerOption{Option: Option{Fetcher: &HTTPFetcher{}}})
+	code, body := serverRequest(t, server, http.MethodGet, "/extract?url="+upstream.URL+"/a.html", "", "")
+	if code != http.StatusForbidden {
+		t.Errorf("状态码 %d，期望 403：%s", code, body)
+	}
+	page := strings.Replace(testFetchPage(), `</body>`, `<div class="pagination"><a href="a_2.html">下一页</a></div></body>`, 1)
+	code, body = serverRequest(t, server, http.MethodPost, "/extract?url="+upstream.URL+"/a.html", "text/html", page)
+	if code != http.StatusOK || !strings.Contains(body, `"pages":1`) {
+		t.Errorf("状态码 %d：%s", code, body)
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("不应访问回环地址，实际访问了 %d 次", n)
+	}
+
+	// 自定义的 Fetcher 与 RoundTripper 同样受限
+	fetched := false
+	fetcher := FetcherFunc(func(ctx context.Context, pageURL string) (string, error) {
+		fetched = true
+		return testFetchPage(), nil
+	})
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		fetched = true
+		return nil, errors.New("不应发出请求")
+	})
+	server = NewServer(ServerOption{Option: Option{Fetcher: fetcher, HTTPClient: &http.Client{Transport: transport}}})
+	code, body = serverRequest(t, server, http.MethodPost, "/extract?url="+upstream.URL+"/a.html", "text/html", page)
+	if code != http.StatusOK || !strings.Contains(body, `"pages":1`) {
+		t.Errorf("状态码 %d：%s", code, body)
+	}
+	if code, body = serverRequest(t, server, http.MethodGet, "/extract?url="+upstream.URL+"/a.html", "", ""); code != http.StatusForbidden {
+		t.Errorf("状态码 %d，期望 403：%s", code, body)
+	}
+	if fetched {
+		t.Error("自定义的 Fetcher 或 RoundTripper 访问了回环地址")
+	}
+
+	for ip, public := range map[string]bool{
+		"93.184.216.34": true, "2606:2800:220:1::": true,
+		"127.0.0.1": false, "::1": false, "10.1.2.3": false, "172.16.0.1": false, "192.168.1.1": false,
+		"169.254.169.254": false, "fe80::1": false, "fd00::1": false, "100.64.0.1": false, "0.0.0.0": false,
+		"::ffff:127.0.0.1": false,
+	} {
+		if got := isPublicIP(net.ParseIP(ip)); got != public {
+			t.Errorf("isPublicIP(%s) = %v", ip, got)
+		}
+	}
+}
+
+func TestParseContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := New(Option{}).ParseContext(ctx, testFetchPage()); err != context.Canceled {
+		t.Errorf("err = %v，期望 context.Canceled", err)
+	}
+}
+
+func TestServerHealthAndMetrics(t *testing.T) {
+	server := NewServer(ServerOption{})
+	if code, body := serverRequest(t, server, http.MethodGet, "/healthz", "", ""); code != http.StatusOK || body != "ok\n" {
+		t.Errorf("健康检查：%d %q", code, body)
+	}
+	serverRequest(t, server, http.MethodPost, "/extract", "text/html", testFetchPage())
+	serverRequest(t, server, http.MethodPost, "/extract", "text/html", "")
+	serverRequest(t, server, http.MethodGet, "/missing", "", "")
+
+	code, body := serverRequest(t, server, http.MethodGet, "/metrics", "", "")
+	if code != http.StatusOK {
+		t.Fatalf("状态码 %d", code)
+	}
+	for _, line := range []string{
+		`readability_http_requests_total{path="/extract",code="200"} 1`,
+		`readability_http_requests_total{path="/extract",code="400"} 1`,
+		`readability_http_requests_total{path="/healthz",code="200"} 1`,
+		`readability_http_requests_total{path="other",code="404"} 1`,
+		`readability_http_request_duration_seconds_count{path="/extract"} 2`,
+		`readability_http_requests_in_flight 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("指标中没有 %s：\n%s", line, body)
+		}
+	}
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// 社交网站的嵌入：推文、微博与 Instagram 的嵌入代码是一个 blockquote 加上渲染脚本，
+// 脚本在预处理时被删除，blockquote 又常因链接密度高被清理。这里在评分之前把它们
+// 整理为只含正文、作者、日期与原文链接的 blockquote，并以 class 标记来源：
+//
+//	<blockquote class="readability-embed readability-embed-twitter">
+//	  <p>正文</p>
+//	  <p>— 作者 · <a href="原文链接">日期</a></p>
+//	</blockquote>
+
+type socialEmbed struct {
+	// 用于 class 的来源名称
+	name string
+	// Article.Embeds 中的来源名称
+	provider string
+	selector string
+	// 原文链接，命名分组 id 为内容 ID
+	permalink *regexp.Regexp
+	// 从嵌入代码的文字中取作者
+	author *regexp.Regexp
+}
+
+var (
+	socialEmbeds = []socialEmbed{
+		{
+			name:      "twitter",
+			provider:  "Twitter",
+			selector:  "blockquote.twitter-tweet, blockquote.twitter-video",
+			permalink: regexp.MustCompile(`(?i)^https?://(?:www\.|mobile\.)?(?:twitter|x)\.com/\w+/status(?:es)?/(?P<id>\d+)`),
+			author:    regexp.MustCompile(`[—–-]\s*(.+?)\s*$`),
+		},
+		{
+			name:      "weibo",
+			provider:  "Weibo",
+			selector:  `blockquote[class*="weibo"], div[class*="weibo-card"], div[class*="weibo-embed"]`,
+			permalink: regexp.MustCompile(`(?i)^https?://(?:(?:www\.)?weibo\.com/\d+/|m\.weibo\.cn/(?:status|detail)/)(?P<id>\w+)`),
+			author:    regexp.MustCompile(`[—–-]\s*(.+?)\s*$`),
+		},
+		{
+			name:      "instagram",
+			provider:  "Instagram",
+			selector:  "blockquote.instagram-media",
+			permalink: regexp.MustCompile(`(?i)^https?://(?:www\.)?instagram\.com/(?:[\w.]+/)?(?:p|reel|tv)/(?P<id>[\w-]+)`),
+			author:    regexp.MustCompile(`(?i)(?:shared by|分享的帖子)\s*(.+?)\s*(?:\bon\b.*)?$`),
+		},
+	}
+	socialEmbedClassPattern = regexp.MustCompile(`(?:^|\s)readability-embed-(\w+)(?:\s|$)`)
+	// 嵌入代码中的提示文字，不属于正文
+	socialBoilerplatePattern = regexp.MustCompile(`(?i)^(view this post on instagram|a post shared by|在 instagram 查看这篇帖子)`)
+)
+
+// 整理社交网站的嵌入，需在删除脚本之后、评分之前调用
+func (read *Readability) replaceSocialEmbeds() {
+	for _, se := range socialEmbeds {
+		read.dom.Find(se.selector).Each(func(i int, s *goquery.Selection) {
+			// 嵌套的嵌入（如引用推文）随外层一起处理，已整理过的不再处理
+			if s.ParentsFiltered(se.selector).Length() > 0 || s.HasClass("readability-embed") {
+				return
+			}
+			if h, ok := se.normalize(s); ok {
+				s.ReplaceWithHtml(h)
+			}
+		})
+	}
+}
+
+func (se socialEmbed) normalize(s *goquery.Selection) (string, bool) {
+	var permalink, date string
+	var link *goquery.Selection
+	if p, has := s.Attr("data-instgrm-permalink"); has && se.permalink.MatchString(p) {
+		permalink = p
+	}
+	// 推文末尾的日期链接即原文链接，正文中的链接可能指向其他推文，因此取最后一个
+	s.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		if href := ts(a.AttrOr("href", "")); se.permalink.MatchString(href) {
+			link = a
+			if len(permalink) == 0 || se.name != "instagram" {
+				permalink = href
+			}
+		}
+	})
+	if len(permalink) == 0 {
+		return "", false
+	}
+	if t := s.Find("time").First(); t.Length() > 0 {
+		date = normalizeSpace(ts(t.Text()))
+		if len(date) == 0 {
+			date = ts(t.AttrOr("datetime", ""))
+		}
+	} else if link != nil && se.name != "instagram" {
+		date = normalizeSpace(ts(link.Text()))
+	}
+
+	// 正文为除原文链接所在段落外的段落，作者取自其余的文字
+	var text []string
+	var rest []string
+	// 没有段落时（如微博卡片）取 class 含 text、content 的元素
+	paragraphSelector := "p"
+	if s.Find("p").Length() == 0 {
+		paragraphSelector = `[class*="text"], [class*="content"]`
+	}
+	paragraphs := s.Find(paragraphSelector)
+	paragraphs.Each(func(i int, p *goquery.Selection) {
+		t := normalizeSpace(ts(p.Text()))
+		switch {
+		case len(t) == 0:
+		case socialBoilerplatePattern.MatchString(t) || (link != nil && p.Find("a").FilterNodes(link.Get(0)).Length() > 0):
+			rest = append(rest, t)
+		default:
+			text = append(text, t)
+		}
+	})
+	clone := s.Clone()
+	clone.Find(paragraphSelector).Remove()
+	if link != nil && se.name != "instagram" {
+		clone.Find("a[href]").FilterFunction(func(i int, a *goquery.Selection) bool {
+			return ts(a.AttrOr("href", "")) == permalink
+		}).Remove()
+	}
+	if t := normalizeSpace(ts(clone.Text())); len(t) > 0 {
+		if paragraphs.Length() == 0 {
+			text = append(text, t)
+		} else {
+			rest = append(rest, t)
+		}
+	}
+	var author string
+	for _, r := range rest {
+		if m := se.author.FindStringSubmatch(r); m != nil {
+			author = m[1]
+			break
+		}
+	}
+	if len(author) == 0 {
+		author = normalizeSpace(ts(s.Find(`[class*="author"], [class*="name"]`).First().Text()))
+	}
+
+	var b strings.Builder
+	b.WriteString(`<blockquote class="readability-embed readability-embed-` + se.name + `">`)
+	for _, t := range text {
+		b.WriteString("<p>" + html.EscapeString(t) + "</p>")
+	}
+	if len(date) == 0 {
+		date = permalink
+	}
+	b.WriteString("<p>— ")
+	if len(author) > 0 {
+		b.WriteString(html.EscapeString(author) + " · ")
+	}
+	b.WriteString(`<a href="` + html.EscapeString(permalink) + `">` + html.EscapeString(date) + "</a></p></blockquote>")
+	return b.String(), true
+}
+
+// 正文中整理过的社交网站嵌入
+func (read *Readability) getSocialEmbed(s *goquery.Selection) (Embed, bool) {
+	m := socialEmbedClassPattern.FindStringSubmatch(s.AttrOr("class", ""))
+	if m == nil {
+		return Embed{}, false
+	}
+	for _, se := range socialEmbeds {
+		if se.name != m[1] {
+			continue
+		}
+		href := s.Find("a[href]").Last().AttrOr("href", "")
+		e := Embed{Provider: se.provider, URL: href}
+		if sm := se.permalink.FindStringSubmatch(href); sm != nil {
+			e.ID = sm[se.permalink.SubexpIndex("id")]
+		}
+		return e, true
+	}
+	return Embed{}, false
+}
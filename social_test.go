@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	testTweet = `<blockquote class="twitter-tweet" data-lang="zh-cn"><p lang="en" dir="ltr">Sunsets don&#39;t get much better than this one over <a href="https://twitter.com/GrandTetonNPS?ref_src=twsrc%5Etfw">@GrandTetonNPS</a>. <a href="https://t.co/YuKy2rcjyU">pic.twitter.com/YuKy2rcjyU</a></p>&mdash; US Department of the Interior (@Interior) <a href="https://twitter.com/Interior/status/463440424141459456?ref_src=twsrc%5Etfw">May 5, 2014</a></blockquote>
+<script async src="https://platform.twitter.com/widgets.js" charset="utf-8"></script>`
+	testInstagram = `<blockquote class="instagram-media" data-instgrm-permalink="https://www.instagram.com/p/B_uf9dmAGPw/?utm_source=ig_embed" data-instgrm-version="12" style="background:#FFF;"><div style="padding:16px;"><a href="https://www.instagram.com/p/B_uf9dmAGPw/?utm_source=ig_embed" target="_blank"><div style="display:flex;"></div><div style="padding:19% 0;"></div><div><div style="color:#3897f0;">View this post on Instagram</div></div></a><p style="margin:8px 0 0 0;">Morning light over the valley</p><p style="color:#c9c8cd;"><a href="https://www.instagram.com/p/B_uf9dmAGPw/?utm_source=ig_embed" target="_blank">A post shared by NASA (@nasa)</a> on <time style="font-family:Arial;" datetime="2020-05-05T16:00:00+00:00">May 5, 2020 at 9:00am PDT</time></p></div></blockquote>
+<script async src="//www.instagram.com/embed.js"></script>`
+	testWeibo = `<div class="weibo-card"><div class="weibo-card-header"><a class="weibo-name" href="https://weibo.com/u/1642591402">新浪新闻</a></div><div class="weibo-text">今天的天气很好，适合出门走走。</div><div class="weibo-footer"><a href="https://weibo.com/1642591402/M2zXk8Hq1">10月16日 12:00</a></div></div>`
+)
+
+func testSocialPage(embed string) string {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的嵌入内容。</p>`
+	return `<html><body><article>` + strings.Repeat(paragraph, 3) + embed + strings.Repeat(paragraph, 3) + `</article></body></html>`
+}
+
+func TestSocialEmbeds(t *testing.T) {
+	cases := []struct {
+		embed   string
+		content string
+		e       Embed
+	}{
+		{
+			testTweet,
+			`<blockquote class="readability-embed readability-embed-twitter"><p>Sunsets don&#39;t get much better than this one over @GrandTetonNPS. pic.twitter.com/YuKy2rcjyU</p><p>— US Department of the Interior (@Interior) · <a href="https://twitter.com/Interior/status/463440424141459456?ref_src=twsrc%5Etfw">May 5, 2014</a></p></blockquote>`,
+			Embed{Provider: "Twitter", ID: "463440424141459456", URL: "https://twitter.com/Interior/status/463440424141459456?ref_src=twsrc%5Etfw"},
+		},
+		{
+			testInstagram,
+			`<blockquote class="readability-embed readability-embed-instagram"><p>Morning light over the valley</p><p>— NASA (@nasa) · <a href="https://www.instagram.com/p/B_uf9dmAGPw/?utm_source=ig_embed">May 5, 2020 at 9:00am PDT</a></p></blockquote>`,
+			Embed{Provider: "Instagram", ID: "B_uf9dmAGPw", URL: "https://www.instagram.com/p/B_uf9dmAGPw/?utm_source=ig_embed"},
+		},
+		{
+			testWeibo,
+			`<blockquote class="readability-embed readability-embed-weibo"><p>今天的天气很好，适合出门走走。</p><p>— 新浪新闻 · <a href="https://weibo.com/1642591402/M2zXk8Hq1">10月16日 12:00</a></p></blockquote>`,
+			Embed{Provider: "Weibo", ID: "M2zXk8Hq1", URL: "https://weibo.com/1642591402/M2zXk8Hq1"},
+		},
+	}
+	for _, c := range cases {
+		article, err := New(Option{PageURL: "https://example.com/post"}).Parse(testSocialPage(c.embed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(article.Content, c.content) {
+			t.Errorf("正文中没有\n%s\n%s", c.content, article.Content)
+		}
+		if !reflect.DeepEqual(article.Embeds, []Embed{c.e}) {
+			t.Errorf("Embeds = %+v，期望 %+v", article.Embeds, c.e)
+		}
+		// 再次解析输出时不应重复整理
+		again, err := New(Option{PageURL: "https://example.com/post"}).Parse("<html><body>" + article.Content + "</body></html>")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(again.Content, c.content) {
+			t.Errorf("再次解析后不一致：\n%s", again.Content)
+		}
+	}
+}
+
+func TestSocialEmbedWithoutPermalink(t *testing.T) {
+	quote := `<blockquote class="twitter-tweet"><p>没有原文链接的引用</p></blockquote>`
+	article, err := New(Option{}).Parse(testSocialPage(quote))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(article.Content, "readability-embed") || len(article.Embeds) > 0 {
+		t.Errorf("没有原文链接时应保持原样：%s", article.Content)
+	}
+}
@@ -0,0 +1,269 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	maxColSpan = 1000
+	maxRowSpan = 65534
+	// 展开后超过此格数的表格不提取
+	maxTableCells = 100000
+)
+
+// Table 正文中的数据表格。合并单元格的值会填入其覆盖的每一格，
+// 因此每一行的列数相同。
+type Table struct {
+	Caption string `json:"caption,omitempty"`
+	// 表头行：<thead> 中的行，没有 <thead> 时为开头全部由 <th> 组成的行
+	Header [][]string `json:"header,omitempty"`
+	Rows   [][]string `json:"rows"`
+}
+
+// WriteCSV 以 CSV 格式输出表头与各行
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(t.Header); err != nil {
+		return err
+	}
+	return cw.WriteAll(t.Rows)
+}
+
+// 表格中的一行，不含嵌套表格中的行
+type tableRow struct {
+	node *html.Node
+	// 位于 <thead> 中
+	head bool
+}
+
+// 按显示顺序列出表格自身的行：<thead> 在前，<tfoot> 在后
+func tableRows(table *html.Node) []tableRow {
+	var head, body, foot []tableRow
+	for c := table.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		switch c.Data {
+		case "tr":
+			body = append(body, tableRow{node: c})
+		case "thead", "tbody", "tfoot":
+			for tr := c.FirstChild; tr != nil; tr = tr.NextSibling {
+				if tr.Type != html.ElementNode || tr.Data != "tr" {
+					continue
+				}
+				switch c.Data {
+				case "thead":
+					head = append(head, tableRow{tr, true})
+				case "tfoot":
+					foot = append(foot, tableRow{tr, false})
+				default:
+					body = append(body, tableRow{tr, false})
+				}
+			}
+		}
+	}
+	return append(append(head, body...), foot...)
+}
+
+func cellSpan(cell *html.Node, key string, max int) int {
+	for _, a := range cell.Attr {
+		if a.Key != key {
+			continue
+		}
+		span, err := strconv.Atoi(ts(a.Val))
+		if err != nil || span < 1 {
+			return 1
+		}
+		if span > max {
+			return max
+		}
+		return span
+	}
+	return 1
+}
+
+// 将表格展开为网格，合并单元格占据其覆盖的每一格，超出表格末行的 rowspan 被截断。
+// 未被任何单元格覆盖的格为 nil。展开后超过 maxTableCells 格时返回 false。
+func tableGrid(rows []tableRow) ([][]*html.Node, bool) {
+	grid := make([][]*html.Node, len(rows))
+	cells := 0
+	for r, row := range rows {
+		col := 0
+		for cell := row.node.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.Type != html.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+				continue
+			}
+			for col < len(grid[r]) && grid[r][col] != nil {
+				col++
+			}
+			colSpan := cellSpan(cell, "colspan", maxColSpan)
+			rowSpan := cellSpan(cell, "rowspan", maxRowSpan)
+			if r+rowSpan > len(grid) {
+				rowSpan = len(grid) - r
+			}
+			if cells += rowSpan * colSpan; cells > maxTableCells || col+colSpan > maxTableCells {
+				return nil, false
+			}
+			for dr := 0; dr < rowSpan; dr++ {
+				for len(grid[r+dr]) < col+colSpan {
+					grid[r+dr] = append(grid[r+dr], nil)
+				}
+				for dc := 0; dc < colSpan; dc++ {
+					grid[r+dr][col+dc] = cell
+				}
+			}
+			col += colSpan
+		}
+	}
+	return grid, true
+}
+
+// 提取数据表格的内容，表格过大时返回 false
+func extractTable(table *goquery.Selection) (Table, bool) {
+	var t Table
+	rows := tableRows(table.Get(0))
+	grid, ok := tableGrid(rows)
+	if !ok {
+		return t, false
+	}
+	if caption := table.ChildrenFiltered("caption"); caption.Length() > 0 {
+		t.Caption = normalizeSpace(ts(caption.First().Text()))
+	}
+	cols := 0
+	for _, row := range grid {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	texts := make(map[*html.Node]string)
+	header := true
+	for r, row := range grid {
+		values := make([]string, cols)
+		allTH := len(row) > 0
+		for c, cell := range row {
+			if cell == nil {
+				allTH = false
+				continue
+			}
+			text, has := texts[cell]
+			if !has {
+				text = normalizeSpace(ts(goquery.NewDocumentFromNode(cell).Text()))
+				texts[cell] = text
+			}
+			values[c] = text
+			allTH = allTH && cell.Data == "th"
+		}
+		// 有 <thead> 时以其为表头，否则为开头全部由 <th> 组成的行
+		if header && (rows[r].head || (!rows[0].head && allTH)) {
+			t.Header = append(t.Header, values)
+			continue
+		}
+		header = false
+		t.Rows = append(t.Rows, values)
+	}
+	return t, true
+}
+
+// 列出正文中的数据表格，需在删除 summary 等属性之前调用
+func (read *Readability) getTables(articleContent *goquery.Selection) []Table {
+	var tables []Table
+	articleContent.Find("table").Each(func(i int, table *goquery.Selection) {
+		isData, marked := read.readabilityDataTable[table.Get(0)]
+		if !marked {
+			// 未经 extract 标记的表格
+			isData = read.isDataTable(table)
+		}
+		if !isData {
+			return
+		}
+		if t, ok := extractTable(table); ok {
+			tables = append(tables, t)
+		}
+	})
+	return tables
+}
+
+// 将布局表格展开为依次排列的 div，使单元格中的内容按段落评分，输出中也不再有表格标记。
+// 需在评分之前调用，此时表格的属性尚未被清除，可以准确区分数据表格与布局表格。
+func (read *Readability) flattenLayoutTables() {
+	tables := read.dom.Find("table")
+	// 先判断所有表格，展开内层表格后外层表格就不再含有嵌套表格
+	layout := make([]bool, tables.Length())
+	tables.Each(func(i int, table *goquery.Selection) {
+		layout[i] = !read.isDataTable(table)
+	})
+	// 逆序处理，嵌套的表格先于外层表格展开
+	for i := tables.Length() - 1; i >= 0; i-- {
+		table := tables.Eq(i)
+		n := table.Get(0)
+		if n.Parent == nil || !layout[i] {
+			continue
+		}
+		var cells []*html.Node
+		if caption := table.ChildrenFiltered("caption"); caption.Length() > 0 {
+			cells = append(cells, caption.Get(0))
+		}
+		for _, row := range tableRows(n) {
+			for c := row.node.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+					cells = append(cells, c)
+				}
+			}
+		}
+		for _, cell := range cells {
+			if cell.FirstChild == nil {
+				continue
+			}
+			// 只含一个元素的单元格（如嵌套的数据表格）不再包一层 div，以免被当作短小的 div 清理
+			if only := onlyElementChild(cell); only != nil {
+				cell.RemoveChild(only)
+				n.Parent.InsertBefore(only, n)
+				continue
+			}
+			div := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+			// 保留 class、id 用于评分
+			for _, a := range cell.Attr {
+				if a.Key == "class" || a.Key == "id" {
+					div.Attr = append(div.Attr, a)
+				}
+			}
+			for cell.FirstChild != nil {
+				c := cell.FirstChild
+				cell.RemoveChild(c)
+				div.AppendChild(c)
+			}
+			n.Parent.InsertBefore(div, n)
+		}
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// n 中唯一的元素，除此之外只有空白；否则为 nil
+func onlyElementChild(n *html.Node) *html.Node {
+	var only *html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.ElementNode:
+			if only != nil {
+				return nil
+			}
+			only = c
+		case c.Type == html.TextNode && len(strings.TrimSpace(c.Data)) == 0:
+		default:
+			return nil
+		}
+	}
+	return only
+}
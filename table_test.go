@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func testTable(t *testing.T, h string) *goquery.Selection {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><body>" + h + "</body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc.Find("table").First()
+}
+
+func TestGetRowAndColumnCount(t *testing.T) {
+	cases := []struct {
+		table      string
+		rows, cols int
+	}{
+		{`<table><tr><td>a</td><td>b</td></tr><tr><td>c</td><td>d</td></tr></table>`, 2, 2},
+		{`<table><tr><td colspan="3">a</td></tr><tr><td>b</td></tr></table>`, 2, 3},
+		{`<table><tr><td rowspan="2">a</td><td>b</td></tr><tr><td>c</td></tr></table>`, 2, 2},
+		{`<table><tr><td rowspan="5">a</td><td>b</td></tr><tr><td>c</td></tr></table>`, 2, 2},
+		{`<table><tr rowspan="3"><td>a</td></tr></table>`, 1, 1},
+		{`<table><tr><td>a<table><tr><td>1</td><td>2</td><td>3</td></tr></table></td></tr></table>`, 1, 1},
+		{`<table><tr><td colspan="x">a</td><td colspan="0">b</td></tr></table>`, 1, 2},
+	}
+	for _, c := range cases {
+		if rows, cols := getRowAndColumnCount(testTable(t, c.table)); rows != c.rows || cols != c.cols {
+			t.Errorf("%s: %d 行 %d 列，期望 %d 行 %d 列", c.table, rows, cols, c.rows, c.cols)
+		}
+	}
+}
+
+func TestIsDataTable(t *testing.T) {
+	row := func(n int) string {
+		return "<tr>" + strings.Repeat("<td>格</td>", n) + "</tr>"
+	}
+	cases := []struct {
+		table string
+		data  bool
+	}{
+		{`<table role="presentation"><thead><tr><th>a</th></tr></thead></table>`, false},
+		{`<table summary="收入"><tr><td>a</td></tr></table>`, true},
+		{`<table><caption>季度收入</caption><tr><td>a</td></tr></table>`, true},
+		{`<table><tr><th>a</th></tr></table>`, true},
+		{`<table><tr><td><table><tr><td>a</td></tr></table></td></tr></table>`, false},
+		{`<table>` + strings.Repeat(row(2), 3) + `</table>`, false},
+		{`<table>` + strings.Repeat(row(3), 4) + `</table>`, true},
+		{`<table>` + strings.Repeat(row(5), 1) + `</table>`, true},
+		{`<table>` + strings.Repeat(row(1), 10) + `</table>`, true},
+	}
+	read := New(Option{})
+	for _, c := range cases {
+		if data := read.isDataTable(testTable(t, c.table)); data != c.data {
+			t.Errorf("%s: %v，期望 %v", c.table, data, c.data)
+		}
+	}
+}
+
+func TestExtractTable(t *testing.T) {
+	table := testTable(t, `<table>
+<caption>2018 年第三季度 <b>财报</b></caption>
+<thead><tr><th rowspan="2">项目</th><th colspan="2">金额（亿元）</th></tr><tr><th>本期</th><th>上年同期</th></tr></thead>
+<tfoot><tr><td>合计</td><td>1,200</td><td>1,000</td></tr></tfoot>
+<tbody><tr><td>营业收入</td><td>800</td><td>700</td></tr><tr><td>其他收入</td><td colspan="2">400 / 300</td></tr></tbody>
+</table>`)
+	want := Table{
+		Caption: "2018 年第三季度 财报",
+		Header:  [][]string{{"项目", "金额（亿元）", "金额（亿元）"}, {"项目", "本期", "上年同期"}},
+		Rows:    [][]string{{"营业收入", "800", "700"}, {"其他收入", "400 / 300", "400 / 300"}, {"合计", "1,200", "1,000"}},
+	}
+	got, ok := extractTable(table)
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("得到 %+v\n期望 %+v", got, want)
+	}
+
+	var b bytes.Buffer
+	if err := got.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	csv := "项目,金额（亿元）,金额（亿元）\n项目,本期,上年同期\n营业收入,800,700\n其他收入,400 / 300,400 / 300\n合计,\"1,200\",\"1,000\"\n"
+	if b.String() != csv {
+		t.Errorf("CSV:\n%s\n期望:\n%s", b.String(), csv)
+	}
+
+	// 没有 thead 时，开头全为 th 的行为表头，缺少的格为空
+	got, _ = extractTable(testTable(t, `<table><tr><th>a</th><th>b</th></tr><tr><td>1</td></tr><tr><th>小计</th><td>2</td></tr></table>`))
+	want = Table{Header: [][]string{{"a", "b"}}, Rows: [][]string{{"1", ""}, {"小计", "2"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("得到 %+v\n期望 %+v", got, want)
+	}
+}
+
+func TestHugeSpans(t *testing.T) {
+	// 合并单元格不展开计数，过大的表格不提取
+	table := `<table><tr>` + strings.Repeat(`<td colspan="1000" rowspan="65534">格</td>`, 300) + `</tr>` + strings.Repeat(`<tr></tr>`, 300) + `</table>`
+	if rows, cols := getRowAndColumnCount(testTable(t, table)); rows != 301 || cols != 300000 {
+		t.Errorf("%d 行 %d 列", rows, cols)
+	}
+	if _, ok := extractTable(testTable(t, table)); ok {
+		t.Error("过大的表格不应提取")
+	}
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的表格。</p>`
+	article, err := New(Option{}).Parse(`<html><body><article>` + strings.Repeat(paragraph, 6) + strings.Replace(table, "<table>", `<table summary="数据">`, 1) + `</article></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(article.Tables) != 0 {
+		t.Errorf("Tables = %d", len(article.Tables))
+	}
+}
+
+func TestParseTables(t *testing.T) {
+	paragraph := `<p>这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文，并且保留其中的表格。</p>`
+	page := `<html><body><article>` + strings.Repeat(paragraph, 3) +
+		`<table summary="主要财务数据"><tr><th>指标</th><th>数值</th></tr><tr><td>营收</td><td>100</td></tr></table>` +
+		`<table><tr><td>布局</td><td>表格</td></tr></table>` +
+		strings.Repeat(paragraph, 3) + `</article></body></html>`
+	article, err := New(Option{}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Table{{Header: [][]string{{"指标", "数值"}}, Rows: [][]string{{"营收", "100"}}}}
+	if !reflect.DeepEqual(article.Tables, want) {
+		t.Errorf("Tables = %+v", article.Tables)
+	}
+	b, err := json.Marshal(article.Tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := `[{"header":[["指标","数值"]],"rows":[["营收","100"]]}]`; string(b) != s {
+		t.Errorf("JSON = %s", b)
+	}
+}
+
+func TestFlattenLayoutTables(t *testing.T) {
+	text := strings.Repeat("这是一段足够长的正文文字，用来让候选节点获得分数，以便提取出正文。", 3)
+	page := `<html><body>
+<table width="960" border="0" cellpadding="0"><tr>
+<td width="200" class="nav"><a href="/">首页</a><br><a href="/news">新闻</a><br><a href="/sports">体育</a></td>
+<td width="760" class="content"><table width="100%"><tr><td><font size="4"><b>文章标题</b></font></td></tr>
+<tr><td>` + text + `<br><br>` + text + `<br><br>` + text + `</td></tr>
+<tr><td><table><tr><th>地区</th><th>人口</th></tr><tr><td>北京</td><td>2154</td></tr></table></td></tr>
+<tr><td>` + text + `</td></tr></table></td>
+</tr></table>
+</body></html>`
+	article, err := New(Option{}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(article.Content, "<table"); n != 1 {
+		t.Errorf("应只保留数据表格，有 %d 个表格：\n%s", n, article.Content)
+	}
+	if strings.Count(article.Content, "这是一段足够长的正文文字") != 12 || strings.Contains(article.Content, "体育") {
+		t.Errorf("正文有误：\n%s", article.Content)
+	}
+	want := []Table{{Header: [][]string{{"地区", "人口"}}, Rows: [][]string{{"北京", "2154"}}}}
+	if !reflect.DeepEqual(article.Tables, want) {
+		t.Errorf("Tables = %+v", article.Tables)
+	}
+}
@@ -0,0 +1,17 @@
+#!/bin/sh
+# 下载 Readability.js 的部分测试页面（Apache License 2.0）到本目录，之后运行
+#
+#	go test -run TestMozillaParity -update
+#
+# 记录基线 testdata/mozilla-parity.json，并将页面与基线一同提交。
+set -e
+REF=${REF:-0.5.0}
+BASE=https://raw.githubusercontent.com/mozilla/readability/$REF
+cd "$(dirname "$0")"
+curl -fsSL -o LICENSE "$BASE/LICENSE.md"
+for page in 001 002 basic-tags-cleaning hidden-nodes rtl-1 rtl-2 wikipedia medium-1 qq gmw youth; do
+	mkdir -p "$page"
+	for f in source.html expected.html expected-metadata.json; do
+		curl -fsSL -o "$page/$f" "$BASE/test/test-pages/$page/$f"
+	done
+done
@@ -0,0 +1,265 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// 文本度量：所有评分规则统一按字符（而非字节）计算长度，
+// 并把中日文标点当作逗号与句末符号，避免中文页面长度被放大三倍。
+
+var (
+	// 视为逗号的字符，包含 Mozilla Readability 中的各语种逗号以及中日文的 "，、；"
+	commaRunes = ",，、；،﹐︐︑⹁⸴⸲"
+	// 句末：英文句点后接空格或结尾，或中日文的 "。！？"
+	sentenceEndPattern = regexp.MustCompile(`\.( |$)|[。！？]`)
+)
+
+// 文本长度（字符数），先去除首尾空白并合并连续空白
+func textLength(s string) int {
+	return utf8.RuneCountInString(normalizeSpace(ts(s)))
+}
+
+// 统计文本中的逗号数量
+func countCommas(s string) int {
+	count := 0
+	for _, r := range s {
+		if strings.ContainsRune(commaRunes, r) {
+			count++
+		}
+	}
+	return count
+}
+
+// 文本中是否含有句末符号
+func hasSentenceEnd(s string) bool {
+	return sentenceEndPattern.MatchString(s)
+}
+
+// 合并连续两个及以上的空白（\t\n\f\r 和空格）为一个空格，与正则 \s{2,} 的替换结果一致
+func normalizeSpace(str string) string {
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(str); {
+		if !isASCIISpace(str[i]) {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(str) && isASCIISpace(str[j]) {
+			j++
+		}
+		if j-i > 1 {
+			if b.Cap() == 0 {
+				b.Grow(len(str))
+			}
+			b.WriteString(str[last:i])
+			b.WriteByte(' ')
+			last = j
+		}
+		i = j
+	}
+	if last == 0 {
+		return str
+	}
+	b.WriteString(str[last:])
+	return b.String()
+}
+
+// 与 normalizeSpace 相同，但不改动 HTML 中 <pre> 的内容，以保留代码的换行与缩进
+func normalizeSpaceOutsidePre(s string) string {
+	var b strings.Builder
+	for {
+		start := indexTag(s, "<pre")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], "</pre>")
+		if end < 0 {
+			break
+		}
+		end += start + len("</pre>")
+		b.WriteString(normalizeSpace(s[:start]))
+		b.WriteString(s[start:end])
+		s = s[end:]
+	}
+	if b.Len() == 0 {
+		return normalizeSpace(s)
+	}
+	b.WriteString(normalizeSpace(s))
+	return b.String()
+}
+
+// 开始标签 tag（如 "<pre"）在 s 中的位置，不匹配 <prefix> 等更长的标签名
+func indexTag(s, tag string) int {
+	for i := 0; ; {
+		j := strings.Index(s[i:], tag)
+		if j < 0 {
+			return -1
+		}
+		i += j + len(tag)
+		if i == len(s) || s[i] == '>' || isASCIISpace(s[i]) || s[i] == '/' {
+			return i - len(tag)
+		}
+	}
+}
+
+func isASCIISpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+// 一段连续空白合并后的长度，以及首尾是否为可合并的 ASCII 空白
+type spaceRun struct {
+	length     int
+	asciiStart bool
+	asciiEnd   bool
+}
+
+func (a spaceRun) join(b spaceRun) spaceRun {
+	if a.length == 0 {
+		return b
+	}
+	if b.length == 0 {
+		return a
+	}
+	r := spaceRun{a.length + b.length, a.asciiStart, b.asciiEnd}
+	if a.asciiEnd && b.asciiStart {
+		r.length--
+	}
+	return r
+}
+
+// 可拼接的文本长度：textLength(a+b) 可由 a、b 各自的 textSpan 得出，
+// 从而自底向上一次算出所有节点的文本长度。零值表示空文本。
+type textSpan struct {
+	// 是否含有非空白字符，为 false 时只有 head 有效
+	content bool
+	// 首尾的空白，会被 ts 去除，但与相邻文本拼接后成为中间的空白
+	head, tail spaceRun
+	// 去除首尾空白并合并连续空白后的字符数
+	length int
+}
+
+func (a textSpan) concat(b textSpan) textSpan {
+	switch {
+	case !a.content && !b.content:
+		return textSpan{head: a.head.join(b.head)}
+	case !a.content:
+		b.head = a.head.join(b.head)
+		return b
+	case !b.content:
+		a.tail = a.tail.join(b.head)
+		return a
+	}
+	return textSpan{
+		content: true,
+		head:    a.head,
+		tail:    b.tail,
+		length:  a.length + a.tail.join(b.head).length + b.length,
+	}
+}
+
+func newTextSpan(s string) textSpan {
+	start := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsSpace(r) })
+	if start < 0 {
+		return textSpan{head: newSpaceRun(s)}
+	}
+	end := strings.LastIndexFunc(s, func(r rune) bool { return !unicode.IsSpace(r) })
+	_, size := utf8.DecodeRuneInString(s[end:])
+	end += size
+	return textSpan{
+		content: true,
+		head:    newSpaceRun(s[:start]),
+		tail:    newSpaceRun(s[end:]),
+		length:  utf8.RuneCountInString(normalizeSpace(s[start:end])),
+	}
+}
+
+func newSpaceRun(s string) spaceRun {
+	if len(s) == 0 {
+		return spaceRun{}
+	}
+	return spaceRun{
+		length:     utf8.RuneCountInString(normalizeSpace(s)),
+		asciiStart: isASCIISpace(s[0]),
+		asciiEnd:   isASCIISpace(s[len(s)-1]),
+	}
+}
+
+// 节点的文本统计，与对 Text() 调用 textLength、countCommas 的结果一致
+type textStats struct {
+	span   textSpan
+	commas int
+	// 子孙节点中所有 <a> 的文本长度之和
+	linkLength int
+}
+
+// 自底向上一次计算 root 下所有节点的文本统计，供评分和清理阶段反复查询，
+// 避免对互相包含的子树重复调用 Text()。DOM 被修改后需调用 clearTextStats。
+func (read *Readability) cacheTextStats(root *goquery.Selection) {
+	read.textStats = make(map[*html.Node]textStats)
+	var walk func(n *html.Node) textStats
+	walk = func(n *html.Node) textStats {
+		var st textStats
+		if n.Type == html.TextNode {
+			st.span = newTextSpan(n.Data)
+			st.commas = countCommas(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			cs := walk(c)
+			st.span = st.span.concat(cs.span)
+			st.commas += cs.commas
+			st.linkLength += cs.linkLength
+			if c.Type == html.ElementNode && c.Data == "a" {
+				st.linkLength += cs.span.length
+			}
+		}
+		read.textStats[n] = st
+		return st
+	}
+	for _, n := range root.Nodes {
+		walk(n)
+	}
+}
+
+func (read *Readability) clearTextStats() {
+	read.textStats = nil
+}
+
+// 节点的文本长度，已缓存时直接返回
+func (read *Readability) textLengthOf(s *goquery.Selection) int {
+	if st, has := read.textStats[s.Get(0)]; has {
+		return st.span.length
+	}
+	return textLength(s.Text())
+}
+
+// 节点中的逗号数量，已缓存时直接返回
+func (read *Readability) commasOf(s *goquery.Selection) int {
+	if st, has := read.textStats[s.Get(0)]; has {
+		return st.commas
+	}
+	return countCommas(s.Text())
+}
+
+// 节点的链接密度，已缓存时直接返回
+func (read *Readability) linkDensity(s *goquery.Selection) float64 {
+	st, has := read.textStats[s.Get(0)]
+	if !has {
+		return getLinkDensity(s)
+	}
+	if st.span.length == 0 {
+		return 0
+	}
+	return float64(st.linkLength) / float64(st.span.length)
+}
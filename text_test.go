@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2018, 奶爸<1@5.nu>
+ * All rights reserved.
+ */
+
+package readability
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestTextLength(t *testing.T) {
+	cases := map[string]int{
+		"":                  0,
+		"  hello  ":         5,
+		"中文字符":              4,
+		"日本語の文章。":           7,
+		" 中文  与 English \n": 12,
+	}
+	for s, l := range cases {
+		if got := textLength(s); got != l {
+			t.Errorf("textLength(%q) = %d，期望 %d", s, got, l)
+		}
+	}
+}
+
+func TestCountCommas(t *testing.T) {
+	cases := map[string]int{
+		"a, b, c":      2,
+		"甲，乙，丙":        2,
+		"苹果、香蕉；橘子，梨":   3,
+		"没有逗号的句子。":     0,
+		"mixed, 混合，文本": 2,
+	}
+	for s, c := range cases {
+		if got := countCommas(s); got != c {
+			t.Errorf("countCommas(%q) = %d，期望 %d", s, got, c)
+		}
+	}
+}
+
+func TestHasSentenceEnd(t *testing.T) {
+	cases := map[string]bool{
+		"An English sentence.": true,
+		"Ends. Then more":      true,
+		"version 1.2 release":  false,
+		"这是一句话。":               true,
+		"真的吗？":                 true,
+		"太好了！":                 true,
+		"没有句末标点":               false,
+		"日本語の文章です。":            true,
+		"列表项、逗号，分号；":           false,
+	}
+	for s, e := range cases {
+		if got := hasSentenceEnd(s); got != e {
+			t.Errorf("hasSentenceEnd(%q) = %v，期望 %v", s, got, e)
+		}
+	}
+}
+
+func TestGetLinkDensityCJK(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<div>正文内容六个<a href="#">链接</a></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 按字符计算为 2/8，按字节计算仍为 2/8；混合中英文时两者不同
+	if d := getLinkDensity(doc.Find("div")); d != 0.25 {
+		t.Errorf("链接密度 %v，期望 0.25", d)
+	}
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader(`<div>abcdef<a href="#">链接</a></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := getLinkDensity(doc.Find("div")); d != 0.25 {
+		t.Errorf("链接密度 %v，期望 0.25", d)
+	}
+}
+
+func TestParseCJKSiblingSentence(t *testing.T) {
+	paragraph := "<p>" + strings.Repeat("这是正文中的一段较长的文字，用来让候选节点获得足够的分数，", 4) + "结束。</p>"
+	page := `<html><head><meta charset="utf-8"><title>测试文章标题示例</title></head><body>
+<article>
+<section class="content">` + strings.Repeat(paragraph, 6) + `</section>
+<p>短句也应保留。</p>
+</article>
+</body></html>`
+	article, err := New(Option{PageURL: "http://example.com/a.html"}).Parse(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(article.Content, "短句也应保留。") {
+		t.Errorf("以中文句号结尾的短段落未被合并：%s", article.Content)
+	}
+}
+
+func TestNormalizeSpace(t *testing.T) {
+	pattern := regexp.MustCompile(`\s{2,}`)
+	for _, s := range []string{"", "a", " a ", "a  b", "a\n\t b\r\n", "  ", "中　　文 \n 字", "a   b\v\vc"} {
+		if got, expected := normalizeSpace(s), pattern.ReplaceAllString(s, " "); got != expected {
+			t.Errorf("normalizeSpace(%q) = %q，期望 %q", s, got, expected)
+		}
+	}
+}
+
+func TestNormalizeSpaceOutsidePre(t *testing.T) {
+	for s, expected := range map[string]string{
+		"<p>a  b</p>": "<p>a b</p>",
+		"<p>a\n\nb</p><pre>x\n    y</pre>\n\n<p>c  d</p>":   "<p>a b</p><pre>x\n    y</pre> <p>c d</p>",
+		"<prefix>a  b</prefix><pre class=\"x\">\n  1</pre>": "<prefix>a b</prefix><pre class=\"x\">\n  1</pre>",
+		"<pre>未闭合  的":                                       "<pre>未闭合 的",
+	} {
+		if got := normalizeSpaceOutsidePre(s); got != expected {
+			t.Errorf("normalizeSpaceOutsidePre(%q) = %q，期望 %q", s, got, expected)
+		}
+	}
+}
+
+// 缓存的文本统计须与直接调用 Text() 的结果完全一致
+func TestCacheTextStats(t *testing.T) {
+	sources, err := filepath.Glob(filepath.Join("testdata", "*", "*", "source.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources = append(sources, "")
+	for _, source := range sources {
+		h := "<div> 　<p>甲 ,<a> 乙\n\n</a></p>  <span>\t</span>丙  <a>d</a></div><p>\n</p>"
+		if len(source) > 0 {
+			b, err := os.ReadFile(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h = string(b)
+		}
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(h))
+		if err != nil {
+			t.Fatal(err)
+		}
+		read := New(Option{})
+		read.cacheTextStats(doc.Selection)
+		doc.Find("*").Each(func(i int, s *goquery.Selection) {
+			if got, expected := read.textLengthOf(s), textLength(s.Text()); got != expected {
+				t.Errorf("%s <%s>: 文本长度 %d，期望 %d", source, s.Get(0).Data, got, expected)
+			}
+			if got, expected := read.commasOf(s), countCommas(s.Text()); got != expected {
+				t.Errorf("%s <%s>: 逗号数 %d，期望 %d", source, s.Get(0).Data, got, expected)
+			}
+			if got, expected := read.linkDensity(s), getLinkDensity(s); got != expected {
+				t.Errorf("%s <%s>: 链接密度 %v，期望 %v", source, s.Get(0).Data, got, expected)
+			}
+		})
+	}
+}